@@ -0,0 +1,101 @@
+package pm
+
+import (
+	"errors"
+	"time"
+)
+
+// AckDecision represents how the message should be settled according to the error returned from the handler.
+type AckDecision int
+
+const (
+	// AckDecisionDefault is the decision for the errors not marked by Permanent, RetryAfter or Nack.
+	// The message is acked when the error is nil, otherwise nacked.
+	AckDecisionDefault AckDecision = iota
+	// AckDecisionPermanent acks the message and reports the error, since redelivering it never succeeds.
+	AckDecisionPermanent
+	// AckDecisionRetryAfter nacks the message after the delay.
+	AckDecisionRetryAfter
+	// AckDecisionNack nacks the message immediately.
+	AckDecisionNack
+)
+
+func (d AckDecision) String() string {
+	switch d {
+	case AckDecisionPermanent:
+		return "permanent"
+	case AckDecisionRetryAfter:
+		return "retry_after"
+	case AckDecisionNack:
+		return "nack"
+	default:
+		return "default"
+	}
+}
+
+// AckDecisionError is the error marked with the ack decision.
+type AckDecisionError struct {
+	Decision AckDecision
+	// Delay is set only when Decision is AckDecisionRetryAfter.
+	Delay time.Duration
+	Err   error
+}
+
+func (e *AckDecisionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AckDecisionError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks the error as permanent failure, the message will be acked and the error will be reported.
+// It's useful for the message which never succeeds even if it's redelivered, e.g. malformed payload.
+// If err is nil, Permanent returns nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &AckDecisionError{Decision: AckDecisionPermanent, Err: err}
+}
+
+// RetryAfter marks the error to be retried after the given delay, the message will be nacked after the delay.
+// Until then, the message is kept outstanding and its lease is extended up to MaxExtension of ReceiveSettings.
+// If err is nil, RetryAfter returns nil.
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &AckDecisionError{Decision: AckDecisionRetryAfter, Delay: delay, Err: err}
+}
+
+// Nack marks the error to be retried immediately, the message will be nacked.
+// If err is nil, Nack returns nil.
+func Nack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &AckDecisionError{Decision: AckDecisionNack, Err: err}
+}
+
+// AckDecisionOf returns the ack decision of the error.
+// When the error is marked multiple times, the outermost one is used.
+func AckDecisionOf(err error) (decision AckDecision, delay time.Duration) {
+	var decisionErr *AckDecisionError
+	if !errors.As(err, &decisionErr) {
+		return AckDecisionDefault, 0
+	}
+	return decisionErr.Decision, decisionErr.Delay
+}
+
+// withAckDecisionOf marks err with the same ack decision as the given marked error.
+func withAckDecisionOf(marked error, err error) error {
+	if err == nil {
+		return nil
+	}
+	var decisionErr *AckDecisionError
+	if !errors.As(marked, &decisionErr) {
+		return err
+	}
+	return &AckDecisionError{Decision: decisionErr.Decision, Delay: decisionErr.Delay, Err: err}
+}
@@ -0,0 +1,86 @@
+package pm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAckDecisionOf(t *testing.T) {
+	t.Parallel()
+
+	baseErr := errors.New("error")
+	tests := []struct {
+		name         string
+		err          error
+		wantDecision AckDecision
+		wantDelay    time.Duration
+	}{
+		{
+			name:         "nil error",
+			err:          nil,
+			wantDecision: AckDecisionDefault,
+		},
+		{
+			name:         "not marked error",
+			err:          baseErr,
+			wantDecision: AckDecisionDefault,
+		},
+		{
+			name:         "permanent error",
+			err:          Permanent(baseErr),
+			wantDecision: AckDecisionPermanent,
+		},
+		{
+			name:         "retry after error",
+			err:          RetryAfter(baseErr, time.Minute),
+			wantDecision: AckDecisionRetryAfter,
+			wantDelay:    time.Minute,
+		},
+		{
+			name:         "nack error",
+			err:          Nack(baseErr),
+			wantDecision: AckDecisionNack,
+		},
+		{
+			name:         "wrapped marked error",
+			err:          fmt.Errorf("wrapped: %w", Permanent(baseErr)),
+			wantDecision: AckDecisionPermanent,
+		},
+		{
+			name:         "outermost decision is used",
+			err:          Nack(Permanent(baseErr)),
+			wantDecision: AckDecisionNack,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			gotDecision, gotDelay := AckDecisionOf(tt.err)
+			if gotDecision != tt.wantDecision {
+				t.Errorf("AckDecisionOf() decision = %v, want %v", gotDecision, tt.wantDecision)
+			}
+			if gotDelay != tt.wantDelay {
+				t.Errorf("AckDecisionOf() delay = %v, want %v", gotDelay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	t.Parallel()
+
+	if err := Permanent(nil); err != nil {
+		t.Errorf("Permanent(nil) = %v, want nil", err)
+	}
+	baseErr := errors.New("error")
+	err := Permanent(baseErr)
+	if !errors.Is(err, baseErr) {
+		t.Errorf("Permanent() is expected to wrap %v", baseErr)
+	}
+	if err.Error() != baseErr.Error() {
+		t.Errorf("Error() = %v, want %v", err.Error(), baseErr.Error())
+	}
+}
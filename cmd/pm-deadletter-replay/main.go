@@ -0,0 +1,154 @@
+// Command pm-deadletter-replay replays the messages dead-lettered by pm_deadletter to their source topic.
+//
+// Usage:
+//
+//	pm-deadletter-replay -project my-project -subscription dead-letter-sub [flags]
+//
+// To run against the Pub/Sub emulator, set PUBSUB_EMULATOR_HOST.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+	"github.com/k-yomo/pm/middleware/pm_deadletter"
+)
+
+// keyValues is a repeatable flag of key=value pairs.
+type keyValues map[string]string
+
+func (k keyValues) String() string {
+	pairs := make([]string, 0, len(k))
+	for key, value := range k {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (k keyValues) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("'%s' must be in the form of key=value", s)
+	}
+	k[key] = value
+	return nil
+}
+
+// stringList is a repeatable flag of strings.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func main() {
+	filterAttrs := keyValues{}
+	setAttrs := keyValues{}
+	var deleteAttrs stringList
+
+	projectID := flag.String("project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "Google Cloud project id")
+	subscriptionID := flag.String("subscription", "", "dead letter subscription id to pull messages from (required)")
+	topicID := flag.String("topic", "", "topic id to replay messages to, defaults to the source topic of each message")
+	filterError := flag.String("filter-error", "", "replay only the messages whose error contains the text")
+	flag.Var(filterAttrs, "filter-attr", "replay only the messages with the attribute key=value (repeatable)")
+	flag.Var(setAttrs, "set-attr", "set the attribute key=value to the replayed messages (repeatable)")
+	flag.Var(&deleteAttrs, "delete-attr", "delete the attribute from the replayed messages (repeatable)")
+	dryRun := flag.Bool("dry-run", false, "print the messages which would be replayed without publishing or acking them")
+	ratePerSecond := flag.Float64("rate", 0, "max messages replayed per second, 0 means unlimited")
+	maxMessages := flag.Int("max", 0, "max messages replayed, 0 means unlimited")
+	idleTimeout := flag.Duration("idle-timeout", pm_deadletter.DefaultReplayIdleTimeout, "finish when no message is received for the duration")
+	flag.Parse()
+
+	if *projectID == "" || *subscriptionID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pubsubClient, err := pubsub.NewClient(ctx, *projectID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pubsubClient.Close()
+
+	opts := []pm_deadletter.ReplayOption{
+		pm_deadletter.WithReplayIdleTimeout(*idleTimeout),
+		pm_deadletter.WithReplayHook(printReplayed(*dryRun)),
+	}
+	for key, value := range filterAttrs {
+		opts = append(opts, pm_deadletter.WithReplayFilter(pm_deadletter.MatchAttribute(key, value)))
+	}
+	if *filterError != "" {
+		opts = append(opts, pm_deadletter.WithReplayFilter(pm_deadletter.MatchError(*filterError)))
+	}
+	if len(setAttrs) > 0 {
+		opts = append(opts, pm_deadletter.WithSetAttributes(setAttrs))
+	}
+	if len(deleteAttrs) > 0 {
+		opts = append(opts, pm_deadletter.WithDeleteAttributes(deleteAttrs...))
+	}
+	if *topicID != "" {
+		topic := pubsubClient.Topic(*topicID)
+		topic.EnableMessageOrdering = true
+		opts = append(opts, pm_deadletter.WithReplayTopic(topic))
+	}
+	if *dryRun {
+		opts = append(opts, pm_deadletter.WithDryRun())
+	}
+	if *ratePerSecond > 0 {
+		opts = append(opts, pm_deadletter.WithRateLimit(*ratePerSecond))
+	}
+	if *maxMessages > 0 {
+		opts = append(opts, pm_deadletter.WithMaxMessages(*maxMessages))
+	}
+
+	report, err := pm_deadletter.Replay(ctx, pm.NewPublisher(pubsubClient), pubsubClient.Subscription(*subscriptionID), opts...)
+	// print the report even on error, e.g. interrupted, so that the operator knows what is already replayed
+	log.Printf("matched: %d, replayed: %d, failed: %d, skipped: %d", report.Matched, report.Replayed, report.Failed, report.Skipped)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func printReplayed(dryRun bool) pm_deadletter.ReplayHook {
+	return func(original *pubsub.Message, topic *pubsub.Topic, replayed *pubsub.Message, err error) {
+		prefix := "replayed"
+		if dryRun {
+			prefix = "[dry-run] would replay"
+		}
+		topicName := ""
+		if topic != nil {
+			topicName = topic.String()
+		}
+		if err != nil {
+			log.Printf("failed to replay message '%s' to '%s': %v", original.ID, topicName, err)
+			return
+		}
+		log.Printf("%s message '%s' to '%s' (error: %q, first failure: %s, attributes: %v)",
+			prefix,
+			original.ID,
+			topicName,
+			original.Attributes[pm_deadletter.ErrorAttribute],
+			original.Attributes[pm_deadletter.FirstFailureTimeAttribute],
+			replayed.Attributes,
+		)
+	}
+}
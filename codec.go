@@ -0,0 +1,163 @@
+package pm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentTypeAttribute is the attribute key for the content type of the message data.
+const ContentTypeAttribute = "content-type"
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+	ContentTypeRaw      = "application/octet-stream"
+)
+
+// Codec marshals / unmarshals the message data.
+type Codec interface {
+	// ContentType returns the content type of the data the codec handles.
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONCodec is a codec for JSON encoding.
+	JSONCodec Codec = jsonCodec{}
+	// ProtobufCodec is a codec for Protocol Buffers binary encoding.
+	// The value must be proto.Message or pointer to proto.Message.
+	ProtobufCodec Codec = protobufCodec{}
+	// RawCodec is a codec passing through the raw bytes.
+	// The value must be []byte for Marshal and *[]byte for Unmarshal.
+	RawCodec Codec = rawCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	// v is a pointer to nil proto.Message like **pb.Message, so allocate the message.
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Ptr {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		if m, ok := rv.Elem().Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("%T is not proto.Message", v)
+}
+
+type rawCodec struct{}
+
+func (rawCodec) ContentType() string {
+	return ContentTypeRaw
+}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%T is not []byte", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("%T is not *[]byte", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// CodecRegistry is a Codec which selects the codec by the content type attribute of each message.
+// When the message doesn't have the content type attribute, the default codec is used.
+type CodecRegistry struct {
+	defaultCodec Codec
+	codecs       map[string]Codec
+}
+
+// NewCodecRegistry initializes new CodecRegistry with the default codec and the additional codecs.
+func NewCodecRegistry(defaultCodec Codec, codecs ...Codec) *CodecRegistry {
+	r := &CodecRegistry{
+		defaultCodec: defaultCodec,
+		codecs:       map[string]Codec{defaultCodec.ContentType(): defaultCodec},
+	}
+	for _, c := range codecs {
+		r.codecs[c.ContentType()] = c
+	}
+	return r
+}
+
+// ContentType returns the content type of the default codec.
+func (r *CodecRegistry) ContentType() string {
+	return r.defaultCodec.ContentType()
+}
+
+// Marshal marshals v with the default codec.
+func (r *CodecRegistry) Marshal(v interface{}) ([]byte, error) {
+	return r.defaultCodec.Marshal(v)
+}
+
+// Unmarshal unmarshals data with the default codec.
+func (r *CodecRegistry) Unmarshal(data []byte, v interface{}) error {
+	return r.defaultCodec.Unmarshal(data, v)
+}
+
+// Lookup returns the codec registered for the content type.
+func (r *CodecRegistry) Lookup(contentType string) (Codec, bool) {
+	c, ok := r.codecs[contentType]
+	return c, ok
+}
+
+// codecForContentType returns the codec to decode the data with the given content type.
+// When the codec is CodecRegistry, the codec is selected from the registry.
+func codecForContentType(codec Codec, contentType string) (Codec, error) {
+	if contentType == "" {
+		return codec, nil
+	}
+	if r, ok := codec.(*CodecRegistry); ok {
+		if c, ok := r.Lookup(contentType); ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("codec for content type '%s' is not registered", contentType)
+	}
+	if codec.ContentType() != contentType {
+		return nil, fmt.Errorf("content type '%s' is not supported by codec for '%s'", contentType, codec.ContentType())
+	}
+	return codec, nil
+}
@@ -0,0 +1,156 @@
+package pm
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestJSONCodec(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+	data, err := JSONCodec.Marshal(payload{Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"name":"test"}`; got != want {
+		t.Errorf("Marshal() = %v, want %v", got, want)
+	}
+
+	var got *payload
+	if err := JSONCodec.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "test" {
+		t.Errorf("Unmarshal() = %v, want %v", got.Name, "test")
+	}
+}
+
+func TestProtobufCodec(t *testing.T) {
+	t.Parallel()
+
+	data, err := ProtobufCodec.Marshal(wrapperspb.String("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unmarshals into proto.Message", func(t *testing.T) {
+		t.Parallel()
+		got := &wrapperspb.StringValue{}
+		if err := ProtobufCodec.Unmarshal(data, got); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(got, wrapperspb.String("test")) {
+			t.Errorf("Unmarshal() = %v, want %v", got, wrapperspb.String("test"))
+		}
+	})
+
+	t.Run("unmarshals into pointer to nil proto.Message", func(t *testing.T) {
+		t.Parallel()
+		var got *wrapperspb.StringValue
+		if err := ProtobufCodec.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(got, wrapperspb.String("test")) {
+			t.Errorf("Unmarshal() = %v, want %v", got, wrapperspb.String("test"))
+		}
+	})
+
+	t.Run("returns error for non proto.Message", func(t *testing.T) {
+		t.Parallel()
+		if _, err := ProtobufCodec.Marshal("test"); err == nil {
+			t.Error("Marshal() is expected to return error")
+		}
+		var got string
+		if err := ProtobufCodec.Unmarshal(data, &got); err == nil {
+			t.Error("Unmarshal() is expected to return error")
+		}
+	})
+}
+
+func TestRawCodec(t *testing.T) {
+	t.Parallel()
+
+	data, err := RawCodec.Marshal([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	if err := RawCodec.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "test" {
+		t.Errorf("Unmarshal() = %v, want %v", string(got), "test")
+	}
+
+	if _, err := RawCodec.Marshal("test"); err == nil {
+		t.Error("Marshal() is expected to return error for non []byte")
+	}
+}
+
+func Test_codecForContentType(t *testing.T) {
+	t.Parallel()
+
+	registry := NewCodecRegistry(JSONCodec, ProtobufCodec)
+	tests := []struct {
+		name        string
+		codec       Codec
+		contentType string
+		want        Codec
+		wantErr     bool
+	}{
+		{
+			name:        "returns the codec when content type is empty",
+			codec:       JSONCodec,
+			contentType: "",
+			want:        JSONCodec,
+		},
+		{
+			name:        "returns the codec when content type matches",
+			codec:       ProtobufCodec,
+			contentType: ContentTypeProtobuf,
+			want:        ProtobufCodec,
+		},
+		{
+			name:        "returns error when content type doesn't match",
+			codec:       JSONCodec,
+			contentType: ContentTypeProtobuf,
+			wantErr:     true,
+		},
+		{
+			name:        "returns the registry when content type is empty",
+			codec:       registry,
+			contentType: "",
+			want:        registry,
+		},
+		{
+			name:        "returns the registered codec for the content type",
+			codec:       registry,
+			contentType: ContentTypeProtobuf,
+			want:        ProtobufCodec,
+		},
+		{
+			name:        "returns error when codec is not registered for the content type",
+			codec:       registry,
+			contentType: "text/plain",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := codecForContentType(tt.codec, tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("codecForContentType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("codecForContentType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -48,7 +48,7 @@ func main() {
 	batchSub := pubsubClient.Subscription("example-topic-batch-sub")
 	err = pubsubSubscriber.HandleSubscriptionFuncMap(map[*pubsub.Subscription]pm.MessageHandler{
 		sub: exampleSubscriptionHandler,
-		batchSub: pm.NewBatchMessageHandler(exampleSubscriptionBatchHandler, pm.BatchMessageHandlerConfig{
+		batchSub: pm.NewContextBatchMessageHandler(exampleSubscriptionBatchHandler, pm.BatchMessageHandlerConfig{
 			DelayThreshold:    100 * time.Millisecond,
 			CountThreshold:    1000,
 			ByteThreshold:     1e6,
@@ -73,6 +73,12 @@ func main() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := pubsubSubscriber.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown subscriber failed", zap.Error(err))
+	}
 }
 
 func exampleSubscriptionHandler(ctx context.Context, m *pubsub.Message) error {
@@ -89,7 +95,7 @@ func exampleSubscriptionHandler(ctx context.Context, m *pubsub.Message) error {
 	return nil
 }
 
-func exampleSubscriptionBatchHandler(messages []*pubsub.Message) error {
+func exampleSubscriptionBatchHandler(ctx context.Context, messages []*pubsub.Message) error {
 	batchErr := make(pm.BatchError)
 	for _, m := range messages {
 		dataStr := string(m.Data)
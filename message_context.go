@@ -0,0 +1,100 @@
+package pm
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/rs/xid"
+)
+
+// messageContext holds the metadata of the message being processed.
+type messageContext struct {
+	message      *pubsub.Message
+	info         *SubscriptionInfo
+	processingID string
+}
+
+type messageContextKey struct{}
+
+// withMessageContext returns the context for processing the given message.
+// processing id is generated for each delivery, so that redeliveries of the same message can be distinguished.
+func withMessageContext(ctx context.Context, info *SubscriptionInfo, m *pubsub.Message) context.Context {
+	return context.WithValue(ctx, messageContextKey{}, &messageContext{
+		message:      m,
+		info:         info,
+		processingID: xid.New().String(),
+	})
+}
+
+// withBatchContext returns the context for processing the batch of messages,
+// which carries the subscription info of the given message's context but not the message.
+// processing id is generated for each batch.
+func withBatchContext(ctx context.Context) context.Context {
+	mc, ok := messageContextFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, messageContextKey{}, &messageContext{
+		info:         mc.info,
+		processingID: xid.New().String(),
+	})
+}
+
+func messageContextFromContext(ctx context.Context) (*messageContext, bool) {
+	mc, ok := ctx.Value(messageContextKey{}).(*messageContext)
+	return mc, ok
+}
+
+// MessageFromContext returns the message being processed.
+// When the context is not passed from the Subscriber or it's passed to the batch handler, ok is false.
+func MessageFromContext(ctx context.Context) (m *pubsub.Message, ok bool) {
+	mc, ok := messageContextFromContext(ctx)
+	if !ok || mc.message == nil {
+		return nil, false
+	}
+	return mc.message, true
+}
+
+// SubscriptionInfoFromContext returns the info of the subscription which the message being processed belongs to.
+// When the context is not passed from the Subscriber, ok is false.
+func SubscriptionInfoFromContext(ctx context.Context) (info *SubscriptionInfo, ok bool) {
+	mc, ok := messageContextFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return mc.info, true
+}
+
+// DeliveryAttemptFromContext returns the delivery attempt of the message being processed.
+// Delivery attempt is set only when the subscription has dead letter policy,
+// so ok is false when it's not set or the context is not passed from the Subscriber.
+func DeliveryAttemptFromContext(ctx context.Context) (deliveryAttempt int, ok bool) {
+	mc, ok := messageContextFromContext(ctx)
+	if !ok || mc.message == nil || mc.message.DeliveryAttempt == nil {
+		return 0, false
+	}
+	return *mc.message.DeliveryAttempt, true
+}
+
+// PublishTimeFromContext returns the publish time of the message being processed.
+// When the context is not passed from the Subscriber or it's passed to the batch handler, ok is false.
+func PublishTimeFromContext(ctx context.Context) (publishTime time.Time, ok bool) {
+	mc, ok := messageContextFromContext(ctx)
+	if !ok || mc.message == nil {
+		return time.Time{}, false
+	}
+	return mc.message.PublishTime, true
+}
+
+// ProcessingIDFromContext returns the id generated by pm for each delivery of the message.
+// Unlike the message id, it differs on every redelivery, so it can be used to correlate the logs of a single processing.
+// For the context passed to the batch handler, the id is generated for each batch.
+// When the context is not passed from the Subscriber, ok is false.
+func ProcessingIDFromContext(ctx context.Context) (processingID string, ok bool) {
+	mc, ok := messageContextFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return mc.processingID, true
+}
@@ -0,0 +1,67 @@
+package pm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestMessageContext(t *testing.T) {
+	t.Parallel()
+
+	deliveryAttempt := 2
+	publishTime := time.Date(2021, 2, 26, 19, 13, 55, 0, time.UTC)
+	m := &pubsub.Message{ID: "message-id", PublishTime: publishTime, DeliveryAttempt: &deliveryAttempt}
+	info := &SubscriptionInfo{TopicID: "test-topic", SubscriptionID: "test-sub"}
+
+	ctx := withMessageContext(context.Background(), info, m)
+
+	if got, ok := MessageFromContext(ctx); !ok || got != m {
+		t.Errorf("MessageFromContext() = %v, %v, want %v, true", got, ok, m)
+	}
+	if got, ok := SubscriptionInfoFromContext(ctx); !ok || got != info {
+		t.Errorf("SubscriptionInfoFromContext() = %v, %v, want %v, true", got, ok, info)
+	}
+	if got, ok := DeliveryAttemptFromContext(ctx); !ok || got != deliveryAttempt {
+		t.Errorf("DeliveryAttemptFromContext() = %v, %v, want %v, true", got, ok, deliveryAttempt)
+	}
+	if got, ok := PublishTimeFromContext(ctx); !ok || !got.Equal(publishTime) {
+		t.Errorf("PublishTimeFromContext() = %v, %v, want %v, true", got, ok, publishTime)
+	}
+	processingID, ok := ProcessingIDFromContext(ctx)
+	if !ok || processingID == "" {
+		t.Errorf("ProcessingIDFromContext() = %v, %v, want non empty id, true", processingID, ok)
+	}
+	if got, _ := ProcessingIDFromContext(withMessageContext(context.Background(), info, m)); got == processingID {
+		t.Errorf("ProcessingIDFromContext() is expected to differ for each processing, got: %v", got)
+	}
+}
+
+func TestMessageContext_notFromSubscriber(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	if _, ok := MessageFromContext(ctx); ok {
+		t.Error("MessageFromContext() is expected to return false")
+	}
+	if _, ok := SubscriptionInfoFromContext(ctx); ok {
+		t.Error("SubscriptionInfoFromContext() is expected to return false")
+	}
+	if _, ok := DeliveryAttemptFromContext(ctx); ok {
+		t.Error("DeliveryAttemptFromContext() is expected to return false")
+	}
+	if _, ok := PublishTimeFromContext(ctx); ok {
+		t.Error("PublishTimeFromContext() is expected to return false")
+	}
+	if _, ok := ProcessingIDFromContext(ctx); ok {
+		t.Error("ProcessingIDFromContext() is expected to return false")
+	}
+
+	// delivery attempt is not set without dead letter policy
+	ctx = withMessageContext(ctx, &SubscriptionInfo{}, &pubsub.Message{ID: "message-id"})
+	if _, ok := DeliveryAttemptFromContext(ctx); ok {
+		t.Error("DeliveryAttemptFromContext() is expected to return false")
+	}
+}
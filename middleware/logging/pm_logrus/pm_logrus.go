@@ -27,7 +27,7 @@ func SubscriptionInterceptor(logger *logrus.Logger, opt ...Option) pm.Subscripti
 		return func(ctx context.Context, m *pubsub.Message) error {
 			startTime := time.Now()
 			entry := logrus.NewEntry(logger)
-			newCtx := newLoggerForProcess(ctx, entry, info, startTime, opts.timestampFormat)
+			newCtx := newLoggerForProcess(ctx, entry, info, m, startTime, opts.timestampFormat)
 
 			err := next(ctxlogrus.ToContext(newCtx, entry), m)
 
@@ -43,7 +43,7 @@ func SubscriptionInterceptor(logger *logrus.Logger, opt ...Option) pm.Subscripti
 	}
 }
 
-func newLoggerForProcess(ctx context.Context, entry *logrus.Entry, info *pm.SubscriptionInfo, start time.Time, timestampFormat string) context.Context {
+func newLoggerForProcess(ctx context.Context, entry *logrus.Entry, info *pm.SubscriptionInfo, m *pubsub.Message, start time.Time, timestampFormat string) context.Context {
 	fields := make(logrus.Fields, 0)
 	fields["pubsub.start_time"] = start.Format(timestampFormat)
 	if d, ok := ctx.Deadline(); ok {
@@ -51,5 +51,15 @@ func newLoggerForProcess(ctx context.Context, entry *logrus.Entry, info *pm.Subs
 	}
 	fields["pubsub.topic_id"] = info.TopicID
 	fields["pubsub.subscription_id"] = info.SubscriptionID
+	fields["pubsub.message_id"] = m.ID
+	if processingID, ok := pm.ProcessingIDFromContext(ctx); ok {
+		fields["pubsub.processing_id"] = processingID
+	}
+	if publishTime, ok := pm.PublishTimeFromContext(ctx); ok {
+		fields["pubsub.publish_time"] = publishTime.Format(timestampFormat)
+	}
+	if deliveryAttempt, ok := pm.DeliveryAttemptFromContext(ctx); ok {
+		fields["pubsub.delivery_attempt"] = deliveryAttempt
+	}
 	return ctxlogrus.ToContext(ctx, entry.WithFields(fields))
 }
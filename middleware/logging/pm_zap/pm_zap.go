@@ -27,7 +27,7 @@ func SubscriptionInterceptor(logger *zap.Logger, opt ...Option) pm.SubscriptionI
 	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
 		return func(ctx context.Context, m *pubsub.Message) error {
 			startTime := time.Now()
-			newCtx := newLoggerForProcess(ctx, logger, info, startTime, opts.timestampFormat)
+			newCtx := newLoggerForProcess(ctx, logger, info, m, startTime, opts.timestampFormat)
 
 			err := next(newCtx, m)
 
@@ -43,12 +43,22 @@ func SubscriptionInterceptor(logger *zap.Logger, opt ...Option) pm.SubscriptionI
 	}
 }
 
-func newLoggerForProcess(ctx context.Context, logger *zap.Logger, info *pm.SubscriptionInfo, start time.Time, timestampFormat string) context.Context {
+func newLoggerForProcess(ctx context.Context, logger *zap.Logger, info *pm.SubscriptionInfo, m *pubsub.Message, start time.Time, timestampFormat string) context.Context {
 	var fields []zapcore.Field
 	fields = append(fields, zap.String("pubsub.start_time", start.Format(timestampFormat)))
 	if d, ok := ctx.Deadline(); ok {
 		fields = append(fields, zap.String("pubsub.deadline", d.Format(timestampFormat)))
 	}
 	fields = append(fields, zap.String("pubsub.topic_id", info.TopicID), zap.String("pubsub.subscription_id", info.SubscriptionID))
+	fields = append(fields, zap.String("pubsub.message_id", m.ID))
+	if processingID, ok := pm.ProcessingIDFromContext(ctx); ok {
+		fields = append(fields, zap.String("pubsub.processing_id", processingID))
+	}
+	if publishTime, ok := pm.PublishTimeFromContext(ctx); ok {
+		fields = append(fields, zap.String("pubsub.publish_time", publishTime.Format(timestampFormat)))
+	}
+	if deliveryAttempt, ok := pm.DeliveryAttemptFromContext(ctx); ok {
+		fields = append(fields, zap.Int("pubsub.delivery_attempt", deliveryAttempt))
+	}
 	return ctxzap.ToContext(ctx, logger.With(fields...))
 }
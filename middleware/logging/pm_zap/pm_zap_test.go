@@ -51,6 +51,9 @@ func TestSubscriptionInterceptor(t *testing.T) {
 			if entry.Message != wantMessage {
 				t.Errorf("INFO log is expected to be emitted, got: %v, want: %v", entry.Message, wantMessage)
 			}
+			if got := entry.ContextMap()["pubsub.message_id"]; got != "message-id" {
+				t.Errorf("pubsub.message_id field is expected to be set, got: %v, want: %v", got, "message-id")
+			}
 		})
 
 		t.Run("Emit error log when processing is successful", func(t *testing.T) {
@@ -2,20 +2,44 @@ package pm_autoack
 
 import (
 	"context"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/k-yomo/pm"
 )
 
 // SubscriptionInterceptor automatically ack / nack subscription based on the returned error.
+// The error marked by pm.Permanent is acked, pm.RetryAfter is nacked after the delay
+// and pm.Nack is nacked immediately. The other non-nil errors are nacked.
+// For pm.RetryAfter, the handler returns after the delay, when the context is done
+// or when the subscriber starts draining, e.g. by Shutdown.
+// The returned error is passed through for the outer interceptors to report it.
 func SubscriptionInterceptor() pm.SubscriptionInterceptor {
 	return func(_ *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
 		return func(ctx context.Context, m *pubsub.Message) error {
 			err := next(ctx, m)
-			if err != nil {
-				m.Nack()
-			} else {
+			if err == nil {
+				m.Ack()
+				return nil
+			}
+
+			switch decision, delay := pm.AckDecisionOf(err); decision {
+			case pm.AckDecisionPermanent:
 				m.Ack()
+			case pm.AckDecisionRetryAfter:
+				// hold the message until the delay passes rather than nacking it asynchronously,
+				// so that the subscriber keeps tracking it as in-flight until it's nacked, e.g. on Shutdown.
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+				case <-pm.DrainSignalFromContext(ctx):
+					timer.Stop()
+				}
+				m.Nack()
+			default:
+				m.Nack()
 			}
 			return err
 		}
@@ -0,0 +1,160 @@
+package pm_autoack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		err               error
+		wantRedelivered   bool
+		wantRedeliveredIn time.Duration
+	}{
+		{
+			name: "acks when the handler returns nil",
+			err:  nil,
+		},
+		{
+			name: "acks when the handler returns permanent error",
+			err:  pm.Permanent(errors.New("error")),
+		},
+		{
+			name:            "nacks when the handler returns error",
+			err:             errors.New("error"),
+			wantRedelivered: true,
+		},
+		{
+			name:            "nacks when the handler returns nack error",
+			err:             pm.Nack(errors.New("error")),
+			wantRedelivered: true,
+		},
+		{
+			name:              "nacks after the delay when the handler returns retry after error",
+			err:               pm.RetryAfter(errors.New("error"), 500*time.Millisecond),
+			wantRedelivered:   true,
+			wantRedeliveredIn: 500 * time.Millisecond,
+		},
+	}
+	for i, tt := range tests {
+		i, tt := i, tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			id := fmt.Sprintf("TestSubscriptionInterceptor_%d_%d", i, time.Now().UnixNano())
+			topic, err := pubsubClient.CreateTopic(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sub, err := pubsubClient.CreateSubscription(context.Background(), id, pubsub.SubscriptionConfig{Topic: topic})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var mu sync.Mutex
+			var deliveredAt []time.Time
+			subscriber := pm.NewSubscriber(pubsubClient, pm.WithSubscriptionInterceptor(SubscriptionInterceptor()))
+			defer subscriber.Close()
+			err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+				mu.Lock()
+				defer mu.Unlock()
+				deliveredAt = append(deliveredAt, time.Now())
+				if len(deliveredAt) > 1 {
+					return nil
+				}
+				return tt.err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			subscriber.Run(context.Background())
+
+			if _, err := topic.Publish(context.Background(), &pubsub.Message{Data: []byte("test")}).Get(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(3 * time.Second)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(deliveredAt) == 0 {
+				t.Fatal("message is expected to be delivered")
+			}
+			if redelivered := len(deliveredAt) > 1; redelivered != tt.wantRedelivered {
+				t.Fatalf("message is redelivered: %v, want %v", redelivered, tt.wantRedelivered)
+			}
+			if tt.wantRedelivered {
+				if got := deliveredAt[1].Sub(deliveredAt[0]); got < tt.wantRedeliveredIn {
+					t.Errorf("message is redelivered in %v, want after %v", got, tt.wantRedeliveredIn)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionInterceptor_retryAfterWhileDraining(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprintf("TestSubscriptionInterceptor_retryAfterWhileDraining_%d", time.Now().UnixNano())
+	topic, err := pubsubClient.CreateTopic(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := pubsubClient.CreateSubscription(context.Background(), id, pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan struct{}, 1)
+	subscriber := pm.NewSubscriber(pubsubClient, pm.WithSubscriptionInterceptor(SubscriptionInterceptor()))
+	defer subscriber.Close()
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		return pm.RetryAfter(errors.New("error"), time.Minute)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriber.Run(context.Background())
+
+	if _, err := topic.Publish(context.Background(), &pubsub.Message{Data: []byte("test")}).Get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message is expected to be received")
+	}
+
+	// the held message is nacked when draining starts instead of waiting for the delay
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := subscriber.Shutdown(ctx)
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v, want nil", err)
+	}
+	if report.Abandoned != 0 {
+		t.Errorf("Shutdown() abandoned %v messages, want 0", report.Abandoned)
+	}
+}
@@ -0,0 +1,187 @@
+package pm_circuitbreaker
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// State represents the state of the circuit breaker.
+type State int
+
+const (
+	// StateClosed lets all messages through.
+	StateClosed State = iota
+	// StateOpen rejects all messages.
+	StateOpen
+	// StateHalfOpen lets the limited number of trial messages through.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker is the circuit breaker for a subscription.
+type breaker struct {
+	subscriptionID string
+	opts           *options
+
+	mu               sync.Mutex
+	state            State
+	windowStart      time.Time
+	requests         int
+	failures         int
+	halfOpenRequests int
+	halfOpenSuccess  int
+	openTimer        *time.Timer
+	// generation is incremented on every state change,
+	// so that the results of the requests allowed in the previous state are ignored.
+	generation int
+	paused     bool
+}
+
+func newBreaker(subscriptionID string, opts *options) *breaker {
+	return &breaker{
+		subscriptionID: subscriptionID,
+		opts:           opts,
+		state:          StateClosed,
+		windowStart:    opts.now(),
+	}
+}
+
+// allow reports whether the message can be processed,
+// and returns the generation of the state it's allowed in, which must be passed to done.
+func (b *breaker) allow() (generation int, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		return b.generation, false
+	case StateHalfOpen:
+		if b.halfOpenRequests >= b.opts.halfOpenMaxRequests {
+			return b.generation, false
+		}
+		b.halfOpenRequests++
+		if b.halfOpenRequests == b.opts.halfOpenMaxRequests {
+			// stop receiving again not to pull more messages than the trial ones until the trials finish
+			b.pause()
+		}
+		return b.generation, true
+	default:
+		return b.generation, true
+	}
+}
+
+// done records the result of the processing allowed by allow.
+// The result of the processing allowed in the previous state is ignored.
+func (b *breaker) done(generation int, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.opts.halfOpenMaxRequests {
+			b.setState(StateClosed)
+			b.resetWindow()
+			b.resume()
+		}
+	case StateClosed:
+		now := b.opts.now()
+		if now.Sub(b.windowStart) > b.opts.window {
+			b.resetWindow()
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.opts.minRequests && float64(b.failures)/float64(b.requests) >= b.opts.failureRatio {
+			b.open()
+		}
+	}
+}
+
+// open opens the circuit and schedules to make it half-open after the open timeout.
+// b.mu must be held by the caller.
+func (b *breaker) open() {
+	b.setState(StateOpen)
+	b.pause()
+	if b.openTimer != nil {
+		b.openTimer.Stop()
+	}
+	b.openTimer = time.AfterFunc(b.opts.openTimeout, b.halfOpen)
+}
+
+func (b *breaker) halfOpen() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateOpen {
+		return
+	}
+	b.halfOpenRequests = 0
+	b.halfOpenSuccess = 0
+	b.setState(StateHalfOpen)
+	// resume to receive the trial messages, it's paused again once they are admitted
+	b.resume()
+}
+
+// pause pauses receiving the subscription when the pauser is set.
+// b.mu must be held by the caller.
+func (b *breaker) pause() {
+	if b.opts.pauser == nil || b.paused {
+		return
+	}
+	b.paused = true
+	if err := b.opts.pauser.Pause(b.subscriptionID); err != nil {
+		log.Printf("pm_circuitbreaker: pause subscription '%s' failed: %v", b.subscriptionID, err)
+	}
+}
+
+// resume resumes receiving the subscription when the pauser is set.
+// b.mu must be held by the caller.
+func (b *breaker) resume() {
+	if b.opts.pauser == nil || !b.paused {
+		return
+	}
+	b.paused = false
+	if err := b.opts.pauser.Resume(b.subscriptionID); err != nil {
+		log.Printf("pm_circuitbreaker: resume subscription '%s' failed: %v", b.subscriptionID, err)
+	}
+}
+
+// setState changes the state and calls the hook.
+// b.mu must be held by the caller.
+func (b *breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	if from != state {
+		b.generation++
+	}
+	if from != state && b.opts.onStateChange != nil {
+		b.opts.onStateChange(b.subscriptionID, from, state)
+	}
+}
+
+// resetWindow resets the counts in the window.
+// b.mu must be held by the caller.
+func (b *breaker) resetWindow() {
+	b.windowStart = b.opts.now()
+	b.requests = 0
+	b.failures = 0
+}
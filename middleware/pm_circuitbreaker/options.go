@@ -0,0 +1,108 @@
+package pm_circuitbreaker
+
+import (
+	"time"
+
+	"github.com/k-yomo/pm"
+)
+
+// Pauser pauses and resumes receiving messages of the subscription, which is implemented by pm.Subscriber.
+type Pauser interface {
+	Pause(subscriptionID string) error
+	Resume(subscriptionID string) error
+}
+
+// StateChangeHook is called when the state of the circuit breaker for the subscription changes.
+// It is called synchronously while the state is locked, so it must not block.
+type StateChangeHook func(subscriptionID string, from, to State)
+
+// FailureFunc decides if the error is counted as failure of the dependency.
+type FailureFunc func(err error) bool
+
+// DefaultFailure counts all errors as failure except for the ones marked by pm.Permanent,
+// which is the problem of the message rather than the dependency.
+func DefaultFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	decision, _ := pm.AckDecisionOf(err)
+	return decision != pm.AckDecisionPermanent
+}
+
+type options struct {
+	failureRatio        float64
+	minRequests         int
+	window              time.Duration
+	openTimeout         time.Duration
+	halfOpenMaxRequests int
+	isFailure           FailureFunc
+	pauser              Pauser
+	onStateChange       StateChangeHook
+	now                 func() time.Time
+}
+
+type Option func(*options)
+
+// WithFailureRatio sets the ratio of failures in the window to open the circuit.
+// Defaults to 0.5.
+func WithFailureRatio(ratio float64) Option {
+	return func(o *options) {
+		o.failureRatio = ratio
+	}
+}
+
+// WithMinRequests sets the min number of requests in the window to evaluate the failure ratio.
+// Defaults to 10.
+func WithMinRequests(n int) Option {
+	return func(o *options) {
+		o.minRequests = n
+	}
+}
+
+// WithWindow sets the duration the requests are counted in.
+// Defaults to 10 seconds.
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// WithOpenTimeout sets the duration the circuit is kept open until it becomes half-open.
+// Defaults to 30 seconds.
+func WithOpenTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = d
+	}
+}
+
+// WithHalfOpenMaxRequests sets the number of trial requests let through in half-open state.
+// The circuit is closed when all of them succeeded. Defaults to 1.
+func WithHalfOpenMaxRequests(n int) Option {
+	return func(o *options) {
+		o.halfOpenMaxRequests = n
+	}
+}
+
+// WithFailure sets the function to decide if the error is counted as failure.
+// Defaults to DefaultFailure.
+func WithFailure(f FailureFunc) Option {
+	return func(o *options) {
+		o.isFailure = f
+	}
+}
+
+// WithPauser pauses receiving messages of the subscription while the circuit is open instead of nacking them.
+// In half-open state, receiving is resumed only until the trial messages are admitted.
+// Typically, pm.Subscriber is passed.
+func WithPauser(pauser Pauser) Option {
+	return func(o *options) {
+		o.pauser = pauser
+	}
+}
+
+// WithStateChangeHook sets the hook called when the state changes.
+func WithStateChangeHook(hook StateChangeHook) Option {
+	return func(o *options) {
+		o.onStateChange = hook
+	}
+}
@@ -0,0 +1,71 @@
+package pm_circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// ErrOpen is returned when the message is nacked since the circuit is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// SubscriptionInterceptor tracks the error rate of the message processing for each subscription,
+// and opens the circuit when the failure ratio in the window exceeds the threshold.
+// While the circuit is open, the messages are nacked and ErrOpen marked by pm.Nack is returned.
+// With WithPauser, receiving messages of the subscription is paused as well, so that they are not redelivered
+// over and over again. After the open timeout, the circuit becomes half-open and the limited number of trial
+// messages are let through, the circuit is closed when all of them succeeded, otherwise opened again.
+// With WithPauser, receiving is resumed in half-open state only until the trial messages are admitted,
+// and resumed again when the circuit is closed.
+//
+//	pubsubSubscriber := pm.NewSubscriber(pubsubClient)
+//	pubsubSubscriber.HandleSubscriptionFunc(
+//		subscription,
+//		handler,
+//		pm.WithInterceptor(pm_circuitbreaker.SubscriptionInterceptor(pm_circuitbreaker.WithPauser(pubsubSubscriber))),
+//	)
+func SubscriptionInterceptor(opt ...Option) pm.SubscriptionInterceptor {
+	opts := options{
+		failureRatio:        0.5,
+		minRequests:         10,
+		window:              10 * time.Second,
+		openTimeout:         30 * time.Second,
+		halfOpenMaxRequests: 1,
+		isFailure:           DefaultFailure,
+		now:                 time.Now,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	var mu sync.Mutex
+	breakers := map[string]*breaker{}
+	breakerFor := func(subscriptionID string) *breaker {
+		mu.Lock()
+		defer mu.Unlock()
+		b, ok := breakers[subscriptionID]
+		if !ok {
+			b = newBreaker(subscriptionID, &opts)
+			breakers[subscriptionID] = b
+		}
+		return b
+	}
+
+	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			b := breakerFor(info.SubscriptionID)
+			generation, ok := b.allow()
+			if !ok {
+				m.Nack()
+				return pm.Nack(ErrOpen)
+			}
+			err := next(ctx, m)
+			b.done(generation, opts.isFailure(err))
+			return err
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package pm_circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+	"github.com/k-yomo/pm"
+)
+
+type fakePauser struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakePauser) Pause(subscriptionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "pause "+subscriptionID)
+	return nil
+}
+
+func (f *fakePauser) Resume(subscriptionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "resume "+subscriptionID)
+	return nil
+}
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var changes []string
+	pauser := &fakePauser{}
+	interceptor := SubscriptionInterceptor(
+		WithMinRequests(2),
+		WithFailureRatio(0.5),
+		WithOpenTimeout(50*time.Millisecond),
+		WithPauser(pauser),
+		WithStateChangeHook(func(subscriptionID string, from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", subscriptionID, from, to))
+		}),
+	)
+
+	fail := true
+	handlerErr := errors.New("error")
+	handler := interceptor(&pm.SubscriptionInfo{SubscriptionID: "sub"}, func(ctx context.Context, m *pubsub.Message) error {
+		if fail {
+			return handlerErr
+		}
+		return nil
+	})
+	otherHandler := interceptor(&pm.SubscriptionInfo{SubscriptionID: "other"}, func(ctx context.Context, m *pubsub.Message) error {
+		return nil
+	})
+
+	// opens after the failures reach the ratio
+	for i := 0; i < 2; i++ {
+		if err := handler(context.Background(), &pubsub.Message{}); !errors.Is(err, handlerErr) {
+			t.Fatalf("error = %v, want %v", err, handlerErr)
+		}
+	}
+	err := handler(context.Background(), &pubsub.Message{})
+	if !errors.Is(err, ErrOpen) {
+		t.Fatalf("error while open = %v, want %v", err, ErrOpen)
+	}
+	if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionNack {
+		t.Errorf("AckDecisionOf() = %v, want %v", decision, pm.AckDecisionNack)
+	}
+	// the breaker is per subscription
+	if err := otherHandler(context.Background(), &pubsub.Message{}); err != nil {
+		t.Errorf("error of another subscription = %v, want nil", err)
+	}
+
+	// half-open after the open timeout, the failed trial opens it again
+	time.Sleep(100 * time.Millisecond)
+	if err := handler(context.Background(), &pubsub.Message{}); !errors.Is(err, handlerErr) {
+		t.Fatalf("error of trial = %v, want %v", err, handlerErr)
+	}
+
+	// the succeeded trial closes it
+	time.Sleep(100 * time.Millisecond)
+	fail = false
+	if err := handler(context.Background(), &pubsub.Message{}); err != nil {
+		t.Fatalf("error of trial = %v, want nil", err)
+	}
+	if err := handler(context.Background(), &pubsub.Message{}); err != nil {
+		t.Fatalf("error after closed = %v, want nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	wantChanges := []string{
+		"sub: closed -> open",
+		"sub: open -> half-open",
+		"sub: half-open -> open",
+		"sub: open -> half-open",
+		"sub: half-open -> closed",
+	}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("state changes (-want +got):\n%s", diff)
+	}
+	// receiving is paused again once the trial is admitted in half-open state
+	wantCalls := []string{"pause sub", "resume sub", "pause sub", "resume sub", "pause sub", "resume sub"}
+	if diff := cmp.Diff(wantCalls, pauser.calls); diff != "" {
+		t.Errorf("pauser calls (-want +got):\n%s", diff)
+	}
+}
+
+func TestSubscriptionInterceptor_halfOpenMaxRequests(t *testing.T) {
+	t.Parallel()
+
+	interceptor := SubscriptionInterceptor(WithMinRequests(1), WithOpenTimeout(10*time.Millisecond), WithHalfOpenMaxRequests(1))
+	release := make(chan struct{})
+	first := true
+	handler := interceptor(&pm.SubscriptionInfo{SubscriptionID: "sub"}, func(ctx context.Context, m *pubsub.Message) error {
+		if first {
+			first = false
+			return errors.New("error")
+		}
+		<-release
+		return nil
+	})
+	_ = handler(context.Background(), &pubsub.Message{})
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = handler(context.Background(), &pubsub.Message{})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := handler(context.Background(), &pubsub.Message{}); !errors.Is(err, ErrOpen) {
+		t.Errorf("error beyond the trial requests = %v, want %v", err, ErrOpen)
+	}
+	close(release)
+	<-done
+}
+
+func TestSubscriptionInterceptor_staleResult(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var changes []string
+	interceptor := SubscriptionInterceptor(
+		WithMinRequests(2),
+		WithOpenTimeout(10*time.Millisecond),
+		WithStateChangeHook(func(subscriptionID string, from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, fmt.Sprintf("%s -> %s", from, to))
+		}),
+	)
+	release := make(chan struct{})
+	handler := interceptor(&pm.SubscriptionInfo{SubscriptionID: "sub"}, func(ctx context.Context, m *pubsub.Message) error {
+		if string(m.Data) == "slow" {
+			<-release
+			return nil
+		}
+		return errors.New("error")
+	})
+
+	// the slow message is allowed while closed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = handler(context.Background(), &pubsub.Message{Data: []byte("slow")})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		_ = handler(context.Background(), &pubsub.Message{})
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// the success of the message allowed while closed doesn't close the half-open circuit
+	close(release)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	wantChanges := []string{"closed -> open", "open -> half-open"}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("state changes (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultFailure(t *testing.T) {
+	t.Parallel()
+
+	if DefaultFailure(nil) {
+		t.Error("DefaultFailure(nil) = true, want false")
+	}
+	if !DefaultFailure(errors.New("error")) {
+		t.Error("DefaultFailure(error) = false, want true")
+	}
+	if DefaultFailure(pm.Permanent(errors.New("error"))) {
+		t.Error("DefaultFailure(permanent error) = true, want false")
+	}
+}
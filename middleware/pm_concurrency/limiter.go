@@ -0,0 +1,162 @@
+package pm_concurrency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Stats represents the statistics of the Limiter.
+type Stats struct {
+	// Size is the max total weight of the concurrent executions.
+	Size int64
+	// InUse is the total weight of the running executions.
+	InUse int64
+	// Waiting is the number of executions waiting for the limiter.
+	Waiting int64
+	// Acquired is the number of executions acquired the limiter so far.
+	Acquired int64
+	// TotalWaitTime is the total time the executions waited for the limiter.
+	TotalWaitTime time.Duration
+	// MaxWaitTime is the longest time an execution waited for the limiter.
+	MaxWaitTime time.Duration
+}
+
+// AverageWaitTime returns the average time the executions waited for the limiter.
+func (s Stats) AverageWaitTime() time.Duration {
+	if s.Acquired == 0 {
+		return 0
+	}
+	return s.TotalWaitTime / time.Duration(s.Acquired)
+}
+
+// Limiter limits the concurrent executions by the weighted semaphore.
+// The same Limiter can be shared across subscriptions to limit the executions globally.
+type Limiter struct {
+	size int64
+	sem  *semaphore.Weighted
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewLimiter initializes Limiter allowing the executions up to the given total weight concurrently.
+func NewLimiter(size int64) *Limiter {
+	return &Limiter{
+		size:  size,
+		sem:   semaphore.NewWeighted(size),
+		stats: Stats{Size: size},
+	}
+}
+
+// Stats returns the statistics of the limiter.
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// acquire blocks until the weight is acquired or ctx is done.
+func (l *Limiter) acquire(ctx context.Context, weight int64) error {
+	weight = l.clamp(weight)
+	l.mu.Lock()
+	l.stats.Waiting++
+	l.mu.Unlock()
+
+	start := time.Now()
+	err := l.sem.Acquire(ctx, weight)
+	wait := time.Since(start)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Waiting--
+	if err != nil {
+		return err
+	}
+	l.acquired(weight, wait)
+	return nil
+}
+
+// tryAcquire acquires the weight without blocking and reports whether it succeeded.
+func (l *Limiter) tryAcquire(weight int64) bool {
+	weight = l.clamp(weight)
+	if !l.sem.TryAcquire(weight) {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.acquired(weight, 0)
+	return true
+}
+
+func (l *Limiter) release(weight int64) {
+	weight = l.clamp(weight)
+	l.mu.Lock()
+	l.stats.InUse -= weight
+	l.mu.Unlock()
+	l.sem.Release(weight)
+}
+
+// acquired updates the stats, l.mu must be held by the caller.
+func (l *Limiter) acquired(weight int64, wait time.Duration) {
+	l.stats.InUse += weight
+	l.stats.Acquired++
+	l.stats.TotalWaitTime += wait
+	if wait > l.stats.MaxWaitTime {
+		l.stats.MaxWaitTime = wait
+	}
+}
+
+// clamp limits the weight up to the size not to block forever.
+func (l *Limiter) clamp(weight int64) int64 {
+	if weight < 1 {
+		return 1
+	}
+	if weight > l.size {
+		return l.size
+	}
+	return weight
+}
+
+// PerSubscriptionLimiters holds Limiter for each subscription, which is created on demand.
+type PerSubscriptionLimiters struct {
+	size int64
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewPerSubscriptionLimiters initializes PerSubscriptionLimiters
+// allowing the executions up to the given total weight concurrently for each subscription.
+func NewPerSubscriptionLimiters(size int64) *PerSubscriptionLimiters {
+	return &PerSubscriptionLimiters{
+		size:     size,
+		limiters: map[string]*Limiter{},
+	}
+}
+
+// Limiter returns the limiter for the subscription.
+func (p *PerSubscriptionLimiters) Limiter(subscriptionID string) *Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	l, ok := p.limiters[subscriptionID]
+	if !ok {
+		l = NewLimiter(p.size)
+		p.limiters[subscriptionID] = l
+	}
+	return l
+}
+
+// Stats returns the statistics of the limiters.
+// The key is subscription id.
+func (p *PerSubscriptionLimiters) Stats() map[string]Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]Stats, len(p.limiters))
+	for subscriptionID, l := range p.limiters {
+		stats[subscriptionID] = l.Stats()
+	}
+	return stats
+}
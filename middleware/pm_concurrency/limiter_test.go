@@ -0,0 +1,73 @@
+package pm_concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Stats(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(2)
+	if err := l.acquire(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		defer close(acquired)
+		if err := l.acquire(context.Background(), 1); err != nil {
+			t.Error(err)
+		}
+	}()
+	for l.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	l.release(2)
+	<-acquired
+
+	got := l.Stats()
+	if got.Size != 2 || got.InUse != 1 || got.Waiting != 0 || got.Acquired != 2 {
+		t.Errorf("Stats() = %+v, want size 2, in use 1, waiting 0, acquired 2", got)
+	}
+	if got.MaxWaitTime < 10*time.Millisecond {
+		t.Errorf("Stats().MaxWaitTime = %v, want >= %v", got.MaxWaitTime, 10*time.Millisecond)
+	}
+	if got.AverageWaitTime() != got.TotalWaitTime/2 {
+		t.Errorf("AverageWaitTime() = %v, want %v", got.AverageWaitTime(), got.TotalWaitTime/2)
+	}
+}
+
+func TestLimiter_clamp(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(2)
+	// the weight larger than the size is capped not to block forever
+	if !l.tryAcquire(10) {
+		t.Fatal("tryAcquire() = false, want true")
+	}
+	if l.tryAcquire(1) {
+		t.Error("tryAcquire() = true, want false")
+	}
+	l.release(10)
+	if got := l.Stats().InUse; got != 0 {
+		t.Errorf("Stats().InUse = %v, want %v", got, 0)
+	}
+}
+
+func TestPerSubscriptionLimiters(t *testing.T) {
+	t.Parallel()
+
+	limiters := NewPerSubscriptionLimiters(1)
+	if limiters.Limiter("a") != limiters.Limiter("a") {
+		t.Error("Limiter() is expected to return the same limiter for the same subscription")
+	}
+	if limiters.Limiter("a") == limiters.Limiter("b") {
+		t.Error("Limiter() is expected to return the different limiter for the different subscription")
+	}
+	if got := len(limiters.Stats()); got != 2 {
+		t.Errorf("len(Stats()) = %v, want %v", got, 2)
+	}
+}
@@ -0,0 +1,30 @@
+package pm_concurrency
+
+import (
+	"cloud.google.com/go/pubsub"
+)
+
+// WeightFunc returns the weight of the message's execution.
+type WeightFunc func(m *pubsub.Message) int64
+
+type options struct {
+	weight         WeightFunc
+	nackOnSaturate bool
+}
+
+type Option func(*options)
+
+// WithWeight sets the function returning the weight of the message's execution.
+// The weight is capped at the size of the limiter. Defaults to 1 for all messages.
+func WithWeight(f WeightFunc) Option {
+	return func(o *options) {
+		o.weight = f
+	}
+}
+
+// WithNackOnSaturation nacks the message immediately instead of blocking until the limiter is available.
+func WithNackOnSaturation() Option {
+	return func(o *options) {
+		o.nackOnSaturate = true
+	}
+}
@@ -0,0 +1,61 @@
+package pm_concurrency
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// ErrSaturated is returned when the message is nacked since the limiter is saturated.
+var ErrSaturated = errors.New("concurrency limit reached")
+
+// SubscriptionInterceptor limits the concurrent executions of the message handlers by the limiter.
+// To limit the executions across subscriptions, register it by pm.WithSubscriptionInterceptor
+// or share the limiter among the subscriptions.
+// By default, the execution blocks until the limiter is available,
+// and the error of the context is returned if the context is done while waiting.
+// With WithNackOnSaturation, the message is nacked and ErrSaturated marked by pm.Nack is returned instead.
+func SubscriptionInterceptor(limiter *Limiter, opt ...Option) pm.SubscriptionInterceptor {
+	opts := newOptions(opt)
+	return func(_ *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			return limit(ctx, m, limiter, opts, next)
+		}
+	}
+}
+
+// PerSubscriptionInterceptor limits the concurrent executions of the message handlers for each subscription.
+func PerSubscriptionInterceptor(limiters *PerSubscriptionLimiters, opt ...Option) pm.SubscriptionInterceptor {
+	opts := newOptions(opt)
+	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			return limit(ctx, m, limiters.Limiter(info.SubscriptionID), opts, next)
+		}
+	}
+}
+
+func newOptions(opt []Option) *options {
+	opts := options{
+		weight: func(*pubsub.Message) int64 { return 1 },
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return &opts
+}
+
+func limit(ctx context.Context, m *pubsub.Message, limiter *Limiter, opts *options, next pm.MessageHandler) error {
+	weight := opts.weight(m)
+	if opts.nackOnSaturate {
+		if !limiter.tryAcquire(weight) {
+			m.Nack()
+			return pm.Nack(ErrSaturated)
+		}
+	} else if err := limiter.acquire(ctx, weight); err != nil {
+		return err
+	}
+	defer limiter.release(weight)
+	return next(ctx, m)
+}
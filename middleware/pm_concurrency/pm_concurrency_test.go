@@ -0,0 +1,118 @@
+package pm_concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("limits concurrent executions across subscriptions", func(t *testing.T) {
+		t.Parallel()
+
+		var running, maxRunning int32
+		handler := func(ctx context.Context, m *pubsub.Message) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}
+
+		interceptor := SubscriptionInterceptor(NewLimiter(2))
+		handlers := []pm.MessageHandler{
+			interceptor(&pm.SubscriptionInfo{SubscriptionID: "a"}, handler),
+			interceptor(&pm.SubscriptionInfo{SubscriptionID: "b"}, handler),
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(h pm.MessageHandler) {
+				defer wg.Done()
+				if err := h(context.Background(), &pubsub.Message{}); err != nil {
+					t.Error(err)
+				}
+			}(handlers[i%2])
+		}
+		wg.Wait()
+
+		if maxRunning > 2 {
+			t.Errorf("max concurrent executions = %v, want <= %v", maxRunning, 2)
+		}
+	})
+
+	t.Run("nacks on saturation", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(1)
+		limiter.tryAcquire(1)
+		handler := SubscriptionInterceptor(limiter, WithNackOnSaturation())(&pm.SubscriptionInfo{}, func(ctx context.Context, m *pubsub.Message) error {
+			t.Error("handler is not expected to be called")
+			return nil
+		})
+		err := handler(context.Background(), &pubsub.Message{})
+		if !errors.Is(err, ErrSaturated) {
+			t.Errorf("error = %v, want %v", err, ErrSaturated)
+		}
+		if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionNack {
+			t.Errorf("AckDecisionOf() = %v, want %v", decision, pm.AckDecisionNack)
+		}
+	})
+
+	t.Run("returns context error while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(1)
+		limiter.tryAcquire(1)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		handler := SubscriptionInterceptor(limiter)(&pm.SubscriptionInfo{}, func(ctx context.Context, m *pubsub.Message) error {
+			t.Error("handler is not expected to be called")
+			return nil
+		})
+		if err := handler(ctx, &pubsub.Message{}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestPerSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	limiters := NewPerSubscriptionLimiters(1)
+	interceptor := PerSubscriptionInterceptor(limiters, WithNackOnSaturation())
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	blockingHandler := interceptor(&pm.SubscriptionInfo{SubscriptionID: "a"}, func(ctx context.Context, m *pubsub.Message) error {
+		close(started)
+		<-block
+		return nil
+	})
+	go func() {
+		_ = blockingHandler(context.Background(), &pubsub.Message{})
+	}()
+	<-started
+	defer close(block)
+
+	noop := func(ctx context.Context, m *pubsub.Message) error { return nil }
+	if err := interceptor(&pm.SubscriptionInfo{SubscriptionID: "a"}, noop)(context.Background(), &pubsub.Message{}); !errors.Is(err, ErrSaturated) {
+		t.Errorf("error for saturated subscription = %v, want %v", err, ErrSaturated)
+	}
+	if err := interceptor(&pm.SubscriptionInfo{SubscriptionID: "b"}, noop)(context.Background(), &pubsub.Message{}); err != nil {
+		t.Errorf("error for another subscription = %v, want nil", err)
+	}
+}
@@ -0,0 +1,56 @@
+package pm_deadletter
+
+import (
+	"sync"
+	"time"
+)
+
+type failure struct {
+	firstFailedAt time.Time
+	count         int
+}
+
+// failureTracker keeps track of the failures of the messages observed by the process.
+// The failures older than ttl are evicted not to keep the messages which are processed by the other processes.
+type failureTracker struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	failures      map[string]*failure
+	lastEvictedAt time.Time
+}
+
+func newFailureTracker(ttl time.Duration) *failureTracker {
+	return &failureTracker{
+		ttl:      ttl,
+		failures: map[string]*failure{},
+	}
+}
+
+// record records the failure of the message and returns the accumulated failure.
+func (f *failureTracker) record(messageID string, now time.Time) failure {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if now.Sub(f.lastEvictedAt) > f.ttl/10 {
+		for id, fl := range f.failures {
+			if now.Sub(fl.firstFailedAt) > f.ttl {
+				delete(f.failures, id)
+			}
+		}
+		f.lastEvictedAt = now
+	}
+
+	fl, ok := f.failures[messageID]
+	if !ok {
+		fl = &failure{firstFailedAt: now}
+		f.failures[messageID] = fl
+	}
+	fl.count++
+	return *fl
+}
+
+func (f *failureTracker) forget(messageID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.failures, messageID)
+}
@@ -0,0 +1,77 @@
+package pm_deadletter
+
+import (
+	"os"
+	"time"
+
+	"github.com/k-yomo/pm"
+)
+
+// DefaultMaxDeliveryAttempts is the default number of delivery attempts until the message is dead-lettered.
+const DefaultMaxDeliveryAttempts = 5
+
+// DefaultFailureTTL is the default duration to keep track of the failures of the message.
+const DefaultFailureTTL = 24 * time.Hour
+
+// PermanentFunc decides if the error is permanent and the message should be dead-lettered immediately.
+type PermanentFunc func(err error) bool
+
+// DefaultPermanent treats the errors marked by pm.Permanent as permanent.
+func DefaultPermanent(err error) bool {
+	decision, _ := pm.AckDecisionOf(err)
+	return decision == pm.AckDecisionPermanent
+}
+
+type options struct {
+	maxDeliveryAttempts int
+	isPermanent         PermanentFunc
+	failureTTL          time.Duration
+	host                string
+	now                 func() time.Time
+}
+
+type Option func(*options)
+
+func defaultOptions() options {
+	host, _ := os.Hostname()
+	return options{
+		maxDeliveryAttempts: DefaultMaxDeliveryAttempts,
+		isPermanent:         DefaultPermanent,
+		failureTTL:          DefaultFailureTTL,
+		host:                host,
+		now:                 time.Now,
+	}
+}
+
+// WithMaxDeliveryAttempts sets the number of delivery attempts until the message is dead-lettered.
+// Defaults to DefaultMaxDeliveryAttempts.
+func WithMaxDeliveryAttempts(n int) Option {
+	return func(o *options) {
+		o.maxDeliveryAttempts = n
+	}
+}
+
+// WithPermanent sets the function to decide if the error is permanent.
+// Defaults to DefaultPermanent.
+func WithPermanent(f PermanentFunc) Option {
+	return func(o *options) {
+		o.isPermanent = f
+	}
+}
+
+// WithFailureTTL sets the duration to keep track of the failures of the message in memory.
+// It should be longer than the time the message is redelivered until it's dead-lettered.
+// Defaults to DefaultFailureTTL.
+func WithFailureTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.failureTTL = d
+	}
+}
+
+// WithHost overwrites the host set to the dead-lettered message.
+// Defaults to os.Hostname().
+func WithHost(host string) Option {
+	return func(o *options) {
+		o.host = host
+	}
+}
@@ -0,0 +1,140 @@
+package pm_deadletter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// The attributes set to the dead-lettered message.
+const (
+	ErrorAttribute              = "pm-deadletter-error"
+	SourceSubscriptionAttribute = "pm-deadletter-source-subscription"
+	SourceTopicAttribute        = "pm-deadletter-source-topic"
+	MessageIDAttribute          = "pm-deadletter-message-id"
+	OrderingKeyAttribute        = "pm-deadletter-ordering-key"
+	DeliveryAttemptAttribute    = "pm-deadletter-delivery-attempt"
+	FirstFailureTimeAttribute   = "pm-deadletter-first-failure-time"
+	HostAttribute               = "pm-deadletter-host"
+)
+
+// The limits of the attributes of Pub/Sub message.
+// See https://cloud.google.com/pubsub/quotas#resource_limits
+const (
+	maxAttributes          = 100
+	maxAttributeValueBytes = 1024
+)
+
+// SubscriptionInterceptor publishes the message to the dead letter topic with the error metadata
+// when the processing failed for the max delivery attempts or failed with the permanent error, then acks the message.
+// The returned error is marked by pm.Permanent, so that the outer interceptors can report it.
+// When publishing to the dead letter topic failed, the error marked by pm.Nack is returned without acking the message.
+// The error message longer than the limit of the attribute value is truncated, and the original attributes
+// exceeding the limit of the number of attributes together with the metadata are dropped in key order.
+//
+// The delivery attempt is taken from the message, which is set only when the subscription has dead letter policy.
+// Otherwise, the failures observed by the process are counted instead, which can be fewer than the actual attempts
+// when the subscription is consumed by multiple processes.
+//
+//	pubsubSubscriber := pm.NewSubscriber(
+//		pubsubClient,
+//		pm.WithSubscriptionInterceptor(
+//			pm_autoack.SubscriptionInterceptor(),
+//			pm_deadletter.SubscriptionInterceptor(publisher, pubsubClient.Topic("dead-letter")),
+//		),
+//	)
+func SubscriptionInterceptor(publisher *pm.Publisher, deadLetterTopic *pubsub.Topic, opt ...Option) pm.SubscriptionInterceptor {
+	opts := defaultOptions()
+	for _, o := range opt {
+		o(&opts)
+	}
+	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		// the failures are tracked for each subscription, since the message id is the same among the subscriptions of the topic
+		tracker := newFailureTracker(opts.failureTTL)
+		return func(ctx context.Context, m *pubsub.Message) error {
+			err := next(ctx, m)
+			if err == nil {
+				tracker.forget(m.ID)
+				return nil
+			}
+
+			fl := tracker.record(m.ID, opts.now())
+			deliveryAttempt := fl.count
+			if m.DeliveryAttempt != nil {
+				deliveryAttempt = *m.DeliveryAttempt
+			}
+			if deliveryAttempt < opts.maxDeliveryAttempts && !opts.isPermanent(err) {
+				return err
+			}
+
+			deadLetterMessage := newDeadLetterMessage(m, info, err, deliveryAttempt, fl.firstFailedAt, opts.host)
+			if _, publishErr := publisher.Publish(ctx, deadLetterTopic, deadLetterMessage).Get(ctx); publishErr != nil {
+				// nack regardless of the decision of err not to lose the message
+				return pm.Nack(fmt.Errorf("publish message '%s' to dead letter topic: %v: %w", m.ID, publishErr, err))
+			}
+			tracker.forget(m.ID)
+			m.Ack()
+			return pm.Permanent(err)
+		}
+	}
+}
+
+func newDeadLetterMessage(m *pubsub.Message, info *pm.SubscriptionInfo, err error, deliveryAttempt int, firstFailedAt time.Time, host string) *pubsub.Message {
+	attrs := make(map[string]string, len(m.Attributes)+8)
+	// keep the first failure time of the message dead-lettered before and replayed
+	if t, parseErr := time.Parse(time.RFC3339Nano, m.Attributes[FirstFailureTimeAttribute]); parseErr == nil && t.Before(firstFailedAt) {
+		firstFailedAt = t
+	}
+
+	attrs[ErrorAttribute] = truncateAttributeValue(err.Error())
+	attrs[SourceSubscriptionAttribute] = resourceName(info.SubscriptionName, info.SubscriptionID)
+	attrs[SourceTopicAttribute] = resourceName(info.TopicName, info.TopicID)
+	attrs[MessageIDAttribute] = m.ID
+	attrs[DeliveryAttemptAttribute] = strconv.Itoa(deliveryAttempt)
+	attrs[FirstFailureTimeAttribute] = firstFailedAt.UTC().Format(time.RFC3339Nano)
+	attrs[HostAttribute] = truncateAttributeValue(host)
+	if m.OrderingKey != "" {
+		attrs[OrderingKeyAttribute] = m.OrderingKey
+	}
+
+	keys := make([]string, 0, len(m.Attributes))
+	for k := range m.Attributes {
+		if _, ok := attrs[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if len(attrs) >= maxAttributes {
+			break
+		}
+		attrs[k] = m.Attributes[k]
+	}
+	return &pubsub.Message{Data: m.Data, Attributes: attrs}
+}
+
+// truncateAttributeValue truncates the value to fit the limit of the attribute value at the rune boundary.
+func truncateAttributeValue(v string) string {
+	if len(v) <= maxAttributeValueBytes {
+		return v
+	}
+	const suffix = "..."
+	n := maxAttributeValueBytes - len(suffix)
+	for n > 0 && !utf8.RuneStart(v[n]) {
+		n--
+	}
+	return v[:n] + suffix
+}
+
+func resourceName(name, id string) string {
+	if name != "" {
+		return name
+	}
+	return id
+}
@@ -0,0 +1,176 @@
+package pm_deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetterTopic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriptionInterceptor_dlq_%d", time.Now().UnixNano()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetterSub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriptionInterceptor_dlq_%d", time.Now().UnixNano()),
+		pubsub.SubscriptionConfig{Topic: deadLetterTopic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 2, 26, 19, 13, 55, 0, time.UTC)
+	interceptor := SubscriptionInterceptor(
+		pm.NewPublisher(pubsubClient),
+		deadLetterTopic,
+		WithMaxDeliveryAttempts(2),
+		WithHost("test-host"),
+		func(o *options) { o.now = func() time.Time { return now } },
+	)
+	info := &pm.SubscriptionInfo{
+		TopicID:          "test-topic",
+		SubscriptionID:   "test-sub",
+		TopicName:        "projects/test/topics/test-topic",
+		SubscriptionName: "projects/test/subscriptions/test-sub",
+	}
+	handlerErr := errors.New("error")
+	handler := interceptor(info, func(ctx context.Context, m *pubsub.Message) error {
+		if string(m.Data) == "permanent" {
+			return pm.Permanent(handlerErr)
+		}
+		return handlerErr
+	})
+
+	m := &pubsub.Message{ID: "1", Data: []byte("transient"), Attributes: map[string]string{"key": "value"}}
+	err = handler(context.Background(), m)
+	if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionDefault {
+		t.Errorf("error before reaching max delivery attempts is expected to be returned as is, got: %v", err)
+	}
+	// the failures of the same message in the other subscription are counted separately
+	otherHandler := interceptor(&pm.SubscriptionInfo{SubscriptionID: "other-sub"}, func(ctx context.Context, m *pubsub.Message) error {
+		return handlerErr
+	})
+	err = otherHandler(context.Background(), m)
+	if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionDefault {
+		t.Errorf("failures in the other subscription are expected to be counted separately, got: %v", err)
+	}
+	err = handler(context.Background(), m)
+	if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionPermanent || !errors.Is(err, handlerErr) {
+		t.Errorf("dead-lettered error is expected to be marked as permanent, got: %v", err)
+	}
+	err = handler(context.Background(), &pubsub.Message{ID: "2", Data: []byte("permanent")})
+	if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionPermanent {
+		t.Errorf("dead-lettered error is expected to be marked as permanent, got: %v", err)
+	}
+
+	want := map[string]map[string]string{
+		"transient": {
+			"key":                       "value",
+			ErrorAttribute:              "error",
+			SourceSubscriptionAttribute: "projects/test/subscriptions/test-sub",
+			SourceTopicAttribute:        "projects/test/topics/test-topic",
+			MessageIDAttribute:          "1",
+			DeliveryAttemptAttribute:    "2",
+			FirstFailureTimeAttribute:   "2021-02-26T19:13:55Z",
+			HostAttribute:               "test-host",
+		},
+		"permanent": {
+			ErrorAttribute:              "error",
+			SourceSubscriptionAttribute: "projects/test/subscriptions/test-sub",
+			SourceTopicAttribute:        "projects/test/topics/test-topic",
+			MessageIDAttribute:          "2",
+			DeliveryAttemptAttribute:    "1",
+			FirstFailureTimeAttribute:   "2021-02-26T19:13:55Z",
+			HostAttribute:               "test-host",
+		},
+	}
+	var mu sync.Mutex
+	got := map[string]map[string]string{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = deadLetterSub.Receive(ctx, func(_ context.Context, m *pubsub.Message) {
+		m.Ack()
+		mu.Lock()
+		defer mu.Unlock()
+		got[string(m.Data)] = m.Attributes
+		if len(got) == len(want) {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dead-lettered messages (-want +got):\n%s", diff)
+	}
+}
+
+func Test_newDeadLetterMessage(t *testing.T) {
+	t.Parallel()
+
+	attrs := map[string]string{}
+	for i := 0; i < maxAttributes; i++ {
+		attrs[fmt.Sprintf("key-%03d", i)] = "value"
+	}
+	m := &pubsub.Message{ID: "1", Attributes: attrs}
+	err := errors.New(strings.Repeat("あ", maxAttributeValueBytes))
+
+	got := newDeadLetterMessage(m, &pm.SubscriptionInfo{SubscriptionID: "sub"}, err, 1, time.Now(), "host")
+	if len(got.Attributes) > maxAttributes {
+		t.Errorf("number of attributes = %v, want <= %v", len(got.Attributes), maxAttributes)
+	}
+	for k, v := range got.Attributes {
+		if len(v) > maxAttributeValueBytes {
+			t.Errorf("length of attribute '%s' = %v, want <= %v", k, len(v), maxAttributeValueBytes)
+		}
+	}
+	if errAttr := got.Attributes[ErrorAttribute]; !utf8.ValidString(errAttr) || !strings.HasSuffix(errAttr, "...") {
+		t.Errorf("error attribute is expected to be truncated at the rune boundary, got: %v", errAttr)
+	}
+	if _, ok := got.Attributes["key-000"]; !ok {
+		t.Error("original attributes are expected to be kept in key order")
+	}
+	if _, ok := got.Attributes[fmt.Sprintf("key-%03d", maxAttributes-1)]; ok {
+		t.Error("original attributes exceeding the limit are expected to be dropped")
+	}
+}
+
+func Test_failureTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newFailureTracker(time.Hour)
+	now := time.Now()
+	if got := tracker.record("1", now); got.count != 1 || !got.firstFailedAt.Equal(now) {
+		t.Errorf("record() = %+v, want count 1 first failed at %v", got, now)
+	}
+	if got := tracker.record("1", now.Add(time.Minute)); got.count != 2 || !got.firstFailedAt.Equal(now) {
+		t.Errorf("record() = %+v, want count 2 first failed at %v", got, now)
+	}
+
+	tracker.forget("1")
+	if got := tracker.record("1", now); got.count != 1 {
+		t.Errorf("record() after forget = %+v, want count 1", got)
+	}
+
+	// failures older than ttl are evicted
+	tracker.record("2", now.Add(2*time.Hour))
+	if _, ok := tracker.failures["1"]; ok {
+		t.Errorf("failure older than ttl is expected to be evicted")
+	}
+}
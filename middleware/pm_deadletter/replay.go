@@ -0,0 +1,342 @@
+package pm_deadletter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+	"golang.org/x/time/rate"
+)
+
+// DefaultReplayIdleTimeout is the default duration to wait for the next message until the replay finishes.
+const DefaultReplayIdleTimeout = 5 * time.Second
+
+// ReplayFilter decides if the dead-lettered message should be replayed.
+type ReplayFilter func(m *pubsub.Message) bool
+
+// MatchAttribute returns ReplayFilter matching the messages which have the attribute with the given value.
+func MatchAttribute(key, value string) ReplayFilter {
+	return func(m *pubsub.Message) bool {
+		v, ok := m.Attributes[key]
+		return ok && v == value
+	}
+}
+
+// MatchError returns ReplayFilter matching the messages whose error contains the given text.
+func MatchError(text string) ReplayFilter {
+	return func(m *pubsub.Message) bool {
+		return strings.Contains(m.Attributes[ErrorAttribute], text)
+	}
+}
+
+// ReplayHook is called for each replayed message.
+// On dry-run, it's called with the message which would be published and nil error.
+type ReplayHook func(original *pubsub.Message, topic *pubsub.Topic, replayed *pubsub.Message, err error)
+
+// ReplayReport represents the result of Replay.
+type ReplayReport struct {
+	// Matched is the number of messages matched with the filters.
+	Matched int
+	// Replayed is the number of messages published to the original topic and acked.
+	Replayed int
+	// Failed is the number of messages failed to be published, which are nacked and kept in the subscription.
+	Failed int
+	// Skipped is the number of messages not matched with the filters or exceeded the max messages,
+	// which are nacked and kept in the subscription.
+	Skipped int
+}
+
+type replayOptions struct {
+	filters        []ReplayFilter
+	editAttributes []func(attrs map[string]string)
+	topic          *pubsub.Topic
+	dryRun         bool
+	rateLimit      rate.Limit
+	maxMessages    int
+	idleTimeout    time.Duration
+	hook           ReplayHook
+}
+
+type ReplayOption func(*replayOptions)
+
+// WithReplayFilter adds the filters, only the messages matched with all of them are replayed.
+func WithReplayFilter(filters ...ReplayFilter) ReplayOption {
+	return func(o *replayOptions) {
+		o.filters = append(o.filters, filters...)
+	}
+}
+
+// WithSetAttributes sets the attributes to the replayed messages.
+func WithSetAttributes(attrs map[string]string) ReplayOption {
+	return func(o *replayOptions) {
+		o.editAttributes = append(o.editAttributes, func(a map[string]string) {
+			for k, v := range attrs {
+				a[k] = v
+			}
+		})
+	}
+}
+
+// WithDeleteAttributes deletes the attributes from the replayed messages.
+func WithDeleteAttributes(keys ...string) ReplayOption {
+	return func(o *replayOptions) {
+		o.editAttributes = append(o.editAttributes, func(a map[string]string) {
+			for _, k := range keys {
+				delete(a, k)
+			}
+		})
+	}
+}
+
+// WithReplayTopic overwrites the topic the messages are replayed to.
+// Defaults to the source topic set to the dead-lettered message.
+// EnableMessageOrdering of the topic must be true to replay the messages with ordering key.
+func WithReplayTopic(topic *pubsub.Topic) ReplayOption {
+	return func(o *replayOptions) {
+		o.topic = topic
+	}
+}
+
+// WithDryRun reports the messages which would be replayed without publishing them, the messages are nacked.
+func WithDryRun() ReplayOption {
+	return func(o *replayOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithRateLimit limits the number of messages replayed per second.
+func WithRateLimit(perSecond float64) ReplayOption {
+	return func(o *replayOptions) {
+		o.rateLimit = rate.Limit(perSecond)
+	}
+}
+
+// WithMaxMessages limits the number of messages replayed.
+func WithMaxMessages(n int) ReplayOption {
+	return func(o *replayOptions) {
+		o.maxMessages = n
+	}
+}
+
+// WithReplayIdleTimeout sets the duration to wait for the next message until the replay finishes.
+// Defaults to DefaultReplayIdleTimeout.
+func WithReplayIdleTimeout(d time.Duration) ReplayOption {
+	return func(o *replayOptions) {
+		o.idleTimeout = d
+	}
+}
+
+// WithReplayHook sets the hook called for each replayed message.
+func WithReplayHook(hook ReplayHook) ReplayOption {
+	return func(o *replayOptions) {
+		o.hook = hook
+	}
+}
+
+// Replay pulls the dead-lettered messages from the subscription and republishes them to their source topic
+// through the publisher, so that the publish interceptors are applied.
+// The metadata attributes set by SubscriptionInterceptor are removed except for FirstFailureTimeAttribute,
+// and the ordering key is restored.
+// Replay finishes when no new message is received for the idle timeout or the max messages are replayed.
+//
+// The messages which are not replayed are nacked and kept in the subscription.
+// Since they are redelivered immediately, the messages already seen are nacked again without being counted.
+// The report of the messages processed so far is returned even when Replay is stopped with error,
+// e.g. the context is canceled.
+func Replay(ctx context.Context, publisher *pm.Publisher, subscription *pubsub.Subscription, opt ...ReplayOption) (*ReplayReport, error) {
+	opts := replayOptions{
+		rateLimit:   rate.Inf,
+		idleTimeout: DefaultReplayIdleTimeout,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	r := &replayer{
+		opts:      &opts,
+		publisher: publisher,
+		limiter:   rate.NewLimiter(opts.rateLimit, 1),
+		seen:      map[string]struct{}{},
+		topics:    map[string]*pubsub.Topic{},
+		report:    &ReplayReport{},
+	}
+	defer r.stopTopics()
+
+	receiveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(opts.idleTimeout, cancel)
+	defer idle.Stop()
+
+	err := subscription.Receive(receiveCtx, func(_ context.Context, m *pubsub.Message) {
+		if !r.markSeen(m.ID) {
+			m.Nack()
+			return
+		}
+		idle.Stop()
+		defer idle.Reset(opts.idleTimeout)
+		// publish with the parent context not to fail publishing the messages being replayed on finish
+		if r.replay(ctx, m) {
+			cancel()
+		}
+	})
+	if err != nil {
+		return r.snapshot(), err
+	}
+	return r.snapshot(), ctx.Err()
+}
+
+type replayer struct {
+	opts      *replayOptions
+	publisher *pm.Publisher
+	limiter   *rate.Limiter
+
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	topics map[string]*pubsub.Topic
+	report *ReplayReport
+}
+
+// markSeen marks the message as seen and returns false if it's already seen.
+func (r *replayer) markSeen(messageID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.seen[messageID]; ok {
+		return false
+	}
+	r.seen[messageID] = struct{}{}
+	return true
+}
+
+// replay replays the message and returns true when the max messages are replayed.
+func (r *replayer) replay(ctx context.Context, m *pubsub.Message) (done bool) {
+	for _, filter := range r.opts.filters {
+		if !filter(m) {
+			m.Nack()
+			r.count(func(report *ReplayReport) { report.Skipped++ })
+			return false
+		}
+	}
+
+	r.mu.Lock()
+	if r.opts.maxMessages > 0 && r.report.Matched >= r.opts.maxMessages {
+		m.Nack()
+		r.report.Skipped++
+		r.mu.Unlock()
+		return true
+	}
+	r.report.Matched++
+	done = r.opts.maxMessages > 0 && r.report.Matched >= r.opts.maxMessages
+	r.mu.Unlock()
+
+	topic, err := r.topic(m)
+	if err != nil {
+		m.Nack()
+		r.count(func(report *ReplayReport) { report.Failed++ })
+		r.callHook(m, nil, nil, err)
+		return done
+	}
+	replayed := r.replayedMessage(m)
+	if r.opts.dryRun {
+		m.Nack()
+		r.callHook(m, topic, replayed, nil)
+		return done
+	}
+
+	if err := r.limiter.Wait(ctx); err != nil {
+		m.Nack()
+		r.count(func(report *ReplayReport) { report.Failed++ })
+		r.callHook(m, topic, replayed, err)
+		return done
+	}
+	if _, err := r.publisher.Publish(ctx, topic, replayed).Get(ctx); err != nil {
+		m.Nack()
+		r.count(func(report *ReplayReport) { report.Failed++ })
+		r.callHook(m, topic, replayed, err)
+		return done
+	}
+	m.Ack()
+	r.count(func(report *ReplayReport) { report.Replayed++ })
+	r.callHook(m, topic, replayed, nil)
+	return done
+}
+
+func (r *replayer) replayedMessage(m *pubsub.Message) *pubsub.Message {
+	attrs := make(map[string]string, len(m.Attributes))
+	for k, v := range m.Attributes {
+		attrs[k] = v
+	}
+	orderingKey := attrs[OrderingKeyAttribute]
+	for _, k := range []string{
+		ErrorAttribute,
+		SourceSubscriptionAttribute,
+		SourceTopicAttribute,
+		MessageIDAttribute,
+		OrderingKeyAttribute,
+		DeliveryAttemptAttribute,
+		HostAttribute,
+	} {
+		delete(attrs, k)
+	}
+	for _, edit := range r.opts.editAttributes {
+		edit(attrs)
+	}
+	return &pubsub.Message{Data: m.Data, Attributes: attrs, OrderingKey: orderingKey}
+}
+
+func (r *replayer) topic(m *pubsub.Message) (*pubsub.Topic, error) {
+	if r.opts.topic != nil {
+		return r.opts.topic, nil
+	}
+
+	name := m.Attributes[SourceTopicAttribute]
+	if name == "" {
+		return nil, fmt.Errorf("source topic of message '%s' is not found in the attributes", m.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if topic, ok := r.topics[name]; ok {
+		return topic, nil
+	}
+	var topic *pubsub.Topic
+	// the source topic is set as the resource name "projects/{project}/topics/{topic}" or the topic id.
+	if parts := strings.Split(name, "/"); len(parts) == 4 {
+		topic = r.publisher.TopicInProject(parts[3], parts[1])
+	} else {
+		topic = r.publisher.Topic(name)
+	}
+	topic.EnableMessageOrdering = true
+	r.topics[name] = topic
+	return topic, nil
+}
+
+func (r *replayer) stopTopics() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, topic := range r.topics {
+		topic.Stop()
+	}
+}
+
+func (r *replayer) count(f func(report *ReplayReport)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r.report)
+}
+
+func (r *replayer) snapshot() *ReplayReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := *r.report
+	return &report
+}
+
+func (r *replayer) callHook(original *pubsub.Message, topic *pubsub.Topic, replayed *pubsub.Message, err error) {
+	if r.opts.hook != nil {
+		r.opts.hook(original, topic, replayed, err)
+	}
+}
@@ -0,0 +1,178 @@
+package pm_deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+	"github.com/k-yomo/pm"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTopicAndSub := func(t *testing.T, name string) (*pubsub.Topic, *pubsub.Subscription) {
+		t.Helper()
+		id := fmt.Sprintf("%s_%d", name, time.Now().UnixNano())
+		topic, err := pubsubClient.CreateTopic(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := pubsubClient.CreateSubscription(context.Background(), id, pubsub.SubscriptionConfig{Topic: topic})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return topic, sub
+	}
+	publishDeadLetters := func(t *testing.T, deadLetterTopic *pubsub.Topic, sourceTopic *pubsub.Topic) {
+		t.Helper()
+		for _, m := range []*pubsub.Message{
+			{Data: []byte("1"), Attributes: map[string]string{"key": "value", ErrorAttribute: "timeout", SourceTopicAttribute: sourceTopic.String(), HostAttribute: "host", FirstFailureTimeAttribute: "2021-02-26T19:13:55Z"}},
+			{Data: []byte("2"), Attributes: map[string]string{ErrorAttribute: "invalid", SourceTopicAttribute: sourceTopic.String()}},
+		} {
+			if _, err := deadLetterTopic.Publish(context.Background(), m).Get(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	receiveAll := func(t *testing.T, sub *pubsub.Subscription) map[string]map[string]string {
+		t.Helper()
+		var mu sync.Mutex
+		got := map[string]map[string]string{}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		err := sub.Receive(ctx, func(_ context.Context, m *pubsub.Message) {
+			m.Ack()
+			mu.Lock()
+			defer mu.Unlock()
+			got[string(m.Data)] = m.Attributes
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("replays filtered messages to the source topic", func(t *testing.T) {
+		t.Parallel()
+
+		sourceTopic, sourceSub := newTopicAndSub(t, "TestReplay_source")
+		deadLetterTopic, deadLetterSub := newTopicAndSub(t, "TestReplay_dlq")
+		publishDeadLetters(t, deadLetterTopic, sourceTopic)
+
+		report, err := Replay(
+			context.Background(),
+			pm.NewPublisher(pubsubClient),
+			deadLetterSub,
+			WithReplayFilter(MatchError("timeout")),
+			WithSetAttributes(map[string]string{"replayed": "true"}),
+			WithDeleteAttributes("key"),
+			WithRateLimit(10),
+			WithReplayIdleTimeout(time.Second),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&ReplayReport{Matched: 1, Replayed: 1, Skipped: 1}, report); diff != "" {
+			t.Errorf("Replay() report (-want +got):\n%s", diff)
+		}
+
+		want := map[string]map[string]string{
+			"1": {"replayed": "true", FirstFailureTimeAttribute: "2021-02-26T19:13:55Z"},
+		}
+		if diff := cmp.Diff(want, receiveAll(t, sourceSub)); diff != "" {
+			t.Errorf("replayed messages (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("doesn't publish on dry-run", func(t *testing.T) {
+		t.Parallel()
+
+		sourceTopic, sourceSub := newTopicAndSub(t, "TestReplay_dryrun_source")
+		deadLetterTopic, deadLetterSub := newTopicAndSub(t, "TestReplay_dryrun_dlq")
+		publishDeadLetters(t, deadLetterTopic, sourceTopic)
+
+		var mu sync.Mutex
+		var hooked []string
+		report, err := Replay(
+			context.Background(),
+			pm.NewPublisher(pubsubClient),
+			deadLetterSub,
+			WithDryRun(),
+			WithReplayIdleTimeout(time.Second),
+			WithReplayHook(func(original *pubsub.Message, topic *pubsub.Topic, replayed *pubsub.Message, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					t.Errorf("ReplayHook is called with error: %v", err)
+				}
+				if topic.String() != sourceTopic.String() {
+					t.Errorf("ReplayHook is called with topic %v, want %v", topic, sourceTopic)
+				}
+				hooked = append(hooked, string(replayed.Data))
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&ReplayReport{Matched: 2}, report); diff != "" {
+			t.Errorf("Replay() report (-want +got):\n%s", diff)
+		}
+		if len(hooked) != 2 {
+			t.Errorf("ReplayHook is called %d times, want %d", len(hooked), 2)
+		}
+		if got := receiveAll(t, sourceSub); len(got) != 0 {
+			t.Errorf("no message is expected to be published on dry-run, got: %v", got)
+		}
+	})
+
+	t.Run("stops after max messages", func(t *testing.T) {
+		t.Parallel()
+
+		sourceTopic, _ := newTopicAndSub(t, "TestReplay_max_source")
+		deadLetterTopic, deadLetterSub := newTopicAndSub(t, "TestReplay_max_dlq")
+		publishDeadLetters(t, deadLetterTopic, sourceTopic)
+
+		report, err := Replay(
+			context.Background(),
+			pm.NewPublisher(pubsubClient),
+			deadLetterSub,
+			WithMaxMessages(1),
+			WithReplayIdleTimeout(time.Minute),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Replayed != 1 {
+			t.Errorf("Replay() replayed %d messages, want %d", report.Replayed, 1)
+		}
+	})
+
+	t.Run("returns the report with error when interrupted", func(t *testing.T) {
+		t.Parallel()
+
+		sourceTopic, _ := newTopicAndSub(t, "TestReplay_interrupted_source")
+		deadLetterTopic, deadLetterSub := newTopicAndSub(t, "TestReplay_interrupted_dlq")
+		publishDeadLetters(t, deadLetterTopic, sourceTopic)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		report, err := Replay(ctx, pm.NewPublisher(pubsubClient), deadLetterSub, WithReplayIdleTimeout(time.Minute))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Replay() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if report == nil || report.Replayed != 2 {
+			t.Errorf("Replay() report = %+v, want replayed %d messages", report, 2)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package pm_keylock
+
+import (
+	"cloud.google.com/go/pubsub"
+)
+
+// KeyFunc returns the key of the message to lock.
+// The message with the empty key is processed without locking.
+type KeyFunc func(m *pubsub.Message) string
+
+// AttributeKey returns KeyFunc which uses the value of the given attribute as the key.
+func AttributeKey(attribute string) KeyFunc {
+	return func(m *pubsub.Message) string {
+		return m.Attributes[attribute]
+	}
+}
+
+// OrderingKey returns KeyFunc which uses the ordering key as the key.
+func OrderingKey() KeyFunc {
+	return func(m *pubsub.Message) string {
+		return m.OrderingKey
+	}
+}
@@ -0,0 +1,62 @@
+package pm_keylock
+
+import (
+	"context"
+	"sync"
+)
+
+// Locker provides mutual exclusion per key.
+type Locker interface {
+	// Lock blocks until the lock of the key is acquired or ctx is done.
+	// The returned channel is closed when the lock is lost while it's held, e.g. the lease couldn't be extended,
+	// nil means the lock is never lost. The returned function releases the lock.
+	Lock(ctx context.Context, key string) (lost <-chan struct{}, unlock func(), err error)
+}
+
+type memoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+}
+
+type memoryLock struct {
+	// ch is the semaphore of size 1, so that waiting can be canceled by the context.
+	ch      chan struct{}
+	waiters int
+}
+
+// NewMemoryLocker initializes Locker which locks the keys within the process.
+func NewMemoryLocker() Locker {
+	return &memoryLocker{locks: map[string]*memoryLock{}}
+}
+
+func (m *memoryLocker) Lock(ctx context.Context, key string) (<-chan struct{}, func(), error) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &memoryLock{ch: make(chan struct{}, 1)}
+		m.locks[key] = l
+	}
+	l.waiters++
+	m.mu.Unlock()
+
+	select {
+	case l.ch <- struct{}{}:
+		return nil, func() {
+			<-l.ch
+			m.release(key, l)
+		}, nil
+	case <-ctx.Done():
+		m.release(key, l)
+		return nil, nil, ctx.Err()
+	}
+}
+
+// release removes the lock from the table when no one holds or waits for it.
+func (m *memoryLocker) release(key string, l *memoryLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l.waiters--
+	if l.waiters == 0 {
+		delete(m.locks, key)
+	}
+}
@@ -0,0 +1,20 @@
+package pm_keylock
+
+import (
+	"time"
+)
+
+type options struct {
+	lockWait time.Duration
+}
+
+type Option func(*options)
+
+// WithLockWait sets the max duration to wait for the lock.
+// When the lock is not acquired within the duration, the message is nacked and ErrLockTimeout is returned.
+// Defaults to 0, which means waiting until the context is done.
+func WithLockWait(d time.Duration) Option {
+	return func(o *options) {
+		o.lockWait = d
+	}
+}
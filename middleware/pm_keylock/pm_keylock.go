@@ -0,0 +1,71 @@
+package pm_keylock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// ErrLockTimeout is returned when the lock is not acquired within the lock wait.
+var ErrLockTimeout = errors.New("lock wait timeout")
+
+// ErrLockLost is returned when the lock is lost while the message is processed.
+var ErrLockLost = errors.New("lock lost")
+
+// SubscriptionInterceptor guarantees that the messages with the same key are not processed at the same time,
+// while the messages with the different keys are processed concurrently.
+// Unlike the message ordering, it doesn't guarantee the order of processing.
+// Use the locker created by NewRedisLocker to exclude the processing among the replicas.
+// When the lock is lost while the message is processed, the context passed to the handler is canceled
+// and ErrLockLost is returned, since the message with the same key may be processed by the other.
+func SubscriptionInterceptor(locker Locker, keyFunc KeyFunc, opt ...Option) pm.SubscriptionInterceptor {
+	opts := options{}
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	return func(_ *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			key := keyFunc(m)
+			if key == "" {
+				return next(ctx, m)
+			}
+
+			lockCtx := ctx
+			if opts.lockWait > 0 {
+				var cancel context.CancelFunc
+				lockCtx, cancel = context.WithTimeout(ctx, opts.lockWait)
+				defer cancel()
+			}
+			lost, unlock, err := locker.Lock(lockCtx, key)
+			if err != nil {
+				if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+					m.Nack()
+					return pm.Nack(fmt.Errorf("lock key '%s': %w", key, ErrLockTimeout))
+				}
+				return fmt.Errorf("lock key '%s': %w", key, err)
+			}
+			defer unlock()
+
+			handlerCtx, cancel := context.WithCancelCause(ctx)
+			defer cancel(nil)
+			if lost != nil {
+				go func() {
+					select {
+					case <-lost:
+						cancel(ErrLockLost)
+					case <-handlerCtx.Done():
+					}
+				}()
+			}
+			err = next(handlerCtx, m)
+			if errors.Is(context.Cause(handlerCtx), ErrLockLost) {
+				return fmt.Errorf("lock key '%s': %w", key, errors.Join(ErrLockLost, err))
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package pm_keylock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		keys           []string
+		wantMaxRunning int32
+	}{
+		{
+			name:           "same key is processed exclusively",
+			keys:           []string{"a", "a", "a"},
+			wantMaxRunning: 1,
+		},
+		{
+			name:           "different keys are processed concurrently",
+			keys:           []string{"a", "b", "c"},
+			wantMaxRunning: 3,
+		},
+		{
+			name:           "empty key is not locked",
+			keys:           []string{"", "", ""},
+			wantMaxRunning: 3,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var running, maxRunning int32
+			handler := SubscriptionInterceptor(NewMemoryLocker(), AttributeKey("customer_id"))(
+				&pm.SubscriptionInfo{},
+				func(ctx context.Context, m *pubsub.Message) error {
+					n := atomic.AddInt32(&running, 1)
+					for {
+						prev := atomic.LoadInt32(&maxRunning)
+						if n <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, n) {
+							break
+						}
+					}
+					time.Sleep(50 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					return nil
+				},
+			)
+
+			var wg sync.WaitGroup
+			for _, key := range tt.keys {
+				key := key
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					m := &pubsub.Message{Attributes: map[string]string{"customer_id": key}}
+					if err := handler(context.Background(), m); err != nil {
+						t.Errorf("unexpected err: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if maxRunning != tt.wantMaxRunning {
+				t.Errorf("max concurrent executions = %v, want %v", maxRunning, tt.wantMaxRunning)
+			}
+		})
+	}
+}
+
+func TestSubscriptionInterceptor_lockWait(t *testing.T) {
+	t.Parallel()
+
+	locker := NewMemoryLocker()
+	_, unlock, err := locker.Lock(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	processed := false
+	handler := SubscriptionInterceptor(locker, OrderingKey(), WithLockWait(10*time.Millisecond))(
+		&pm.SubscriptionInfo{},
+		func(ctx context.Context, m *pubsub.Message) error {
+			processed = true
+			return nil
+		},
+	)
+
+	err = handler(context.Background(), &pubsub.Message{OrderingKey: "a"})
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("ErrLockTimeout is expected, got: %v", err)
+	}
+	if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionNack {
+		t.Errorf("ack decision = %v, want %v", decision, pm.AckDecisionNack)
+	}
+	if processed {
+		t.Error("message is not expected to be processed")
+	}
+}
+
+type lostLocker struct {
+	lost chan struct{}
+}
+
+func (l *lostLocker) Lock(ctx context.Context, key string) (<-chan struct{}, func(), error) {
+	return l.lost, func() {}, nil
+}
+
+func TestSubscriptionInterceptor_lockLost(t *testing.T) {
+	t.Parallel()
+
+	locker := &lostLocker{lost: make(chan struct{})}
+	handler := SubscriptionInterceptor(locker, OrderingKey())(
+		&pm.SubscriptionInfo{},
+		func(ctx context.Context, m *pubsub.Message) error {
+			close(locker.lost)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	)
+
+	err := handler(context.Background(), &pubsub.Message{OrderingKey: "a"})
+	if !errors.Is(err, ErrLockLost) {
+		t.Errorf("ErrLockLost is expected, got: %v", err)
+	}
+}
+
+func Test_memoryLocker_Lock(t *testing.T) {
+	t.Parallel()
+
+	locker := NewMemoryLocker().(*memoryLocker)
+	_, unlock, err := locker.Lock(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := locker.Lock(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("locking the held key is expected to wait until the context is done, got: %v", err)
+	}
+
+	unlock()
+	if len(locker.locks) != 0 {
+		t.Errorf("lock table is expected to be empty after unlock, got: %v", len(locker.locks))
+	}
+}
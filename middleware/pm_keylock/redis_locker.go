@@ -0,0 +1,116 @@
+package pm_keylock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/xid"
+)
+
+// redisLockRetryInterval is the interval to retry acquiring the lock held by the other.
+const redisLockRetryInterval = 50 * time.Millisecond
+
+// unlockScript deletes the lock only when it's still held by the token.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// extendScript extends the lease only when the lock is still held by the token.
+var extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+type redisLocker struct {
+	redisClient *redis.Client
+	keyPrefix   string
+	lease       time.Duration
+}
+
+// NewRedisLocker initializes Locker which locks the keys by redis, so that the keys are locked among the replicas.
+// The lock expires after the lease unless it's extended, which is done periodically while the lock is held,
+// so that the lock held by the crashed process is released after the lease.
+// The lock is lost when it's taken by the other, or it couldn't be extended within the lease.
+// It panics when the lease is shorter than 1 millisecond, which is the precision of the expiry in redis.
+func NewRedisLocker(redisClient *redis.Client, keyPrefix string, lease time.Duration) Locker {
+	if lease < time.Millisecond {
+		panic(fmt.Sprintf("pm_keylock: lease must be at least 1ms, got %v", lease))
+	}
+	return &redisLocker{
+		redisClient: redisClient,
+		keyPrefix:   keyPrefix,
+		lease:       lease,
+	}
+}
+
+func (r *redisLocker) Lock(ctx context.Context, key string) (<-chan struct{}, func(), error) {
+	key = r.keyPrefix + ":" + key
+	token := xid.New().String()
+	var acquiredAt time.Time
+	for {
+		acquiredAt = time.Now()
+		ok, err := r.redisClient.SetNX(ctx, key, token, r.lease).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-time.After(redisLockRetryInterval):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	extendCtx, stopExtend := context.WithCancel(context.Background())
+	lost := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if !r.extend(extendCtx, key, token, acquiredAt) {
+			close(lost)
+		}
+	}()
+	return lost, func() {
+		stopExtend()
+		<-done
+		// the error is ignored since the lock is released after the lease anyway
+		_ = unlockScript.Run(context.Background(), r.redisClient, []string{key}, token).Err()
+	}, nil
+}
+
+// extend extends the lease of the lock until ctx is done.
+// It returns false when the lock is lost, that is, the lock is held by the other
+// or the lease expired without being extended.
+func (r *redisLocker) extend(ctx context.Context, key, token string, extendedAt time.Time) bool {
+	ticker := time.NewTicker(r.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			extended, err := extendScript.Run(ctx, r.redisClient, []string{key}, token, r.lease.Milliseconds()).Int()
+			if ctx.Err() != nil {
+				return true
+			}
+			if err == nil && extended == 1 {
+				extendedAt = now
+				continue
+			}
+			// the failed extension is retried on the next tick while the lease remains
+			if err == nil || time.Since(extendedAt) >= r.lease {
+				return false
+			}
+		case <-ctx.Done():
+			return true
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pm_keylock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/xid"
+)
+
+func Test_redisLocker_Lock(t *testing.T) {
+	t.Parallel()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_URL")})
+	locker := NewRedisLocker(redisClient, xid.New().String(), 300*time.Millisecond)
+
+	lost, unlock, err := locker.Lock(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("redisLocker.Lock returned err: %v", err)
+	}
+
+	// the lease is extended while the lock is held
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, _, err := locker.Lock(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("locking the held key is expected to wait until the context is done, got: %v", err)
+	}
+
+	select {
+	case <-lost:
+		t.Error("the lock is not expected to be lost while the lease is extended")
+	default:
+	}
+
+	unlock()
+	_, unlock2, err := locker.Lock(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("redisLocker.Lock is expected to succeed after unlock, got err: %v", err)
+	}
+	unlock2()
+}
+
+func Test_redisLocker_Lock_lost(t *testing.T) {
+	t.Parallel()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_URL")})
+	keyPrefix := xid.New().String()
+	locker := NewRedisLocker(redisClient, keyPrefix, 300*time.Millisecond)
+
+	lost, unlock, err := locker.Lock(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("redisLocker.Lock returned err: %v", err)
+	}
+	defer unlock()
+
+	// the lock is taken by the other
+	if err := redisClient.Set(context.Background(), keyPrefix+":a", "other", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lost:
+	case <-time.After(1 * time.Second):
+		t.Error("the lock is expected to be lost")
+	}
+}
+
+func TestNewRedisLocker_invalidLease(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		lease time.Duration
+	}{
+		{name: "zero", lease: 0},
+		{name: "shorter than 1ms", lease: time.Millisecond - 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRedisLocker is expected to panic with the lease %v", tt.lease)
+				}
+			}()
+			NewRedisLocker(redis.NewClient(&redis.Options{}), "prefix", tt.lease)
+		})
+	}
+}
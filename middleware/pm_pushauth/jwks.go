@@ -0,0 +1,204 @@
+package pm_pushauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// GoogleJWKSURL is the url of JWKS to verify the token signed by Google.
+const GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// ErrKeyNotFound is returned from KeySource when the key for the key id is not found.
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeySource provides the public keys to verify the token signature.
+type KeySource interface {
+	PublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, error)
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks failed: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("parse modulus of key '%s' failed: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("parse exponent of key '%s' failed: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+type staticKeySource struct {
+	keys map[string]*rsa.PublicKey
+}
+
+// NewStaticKeySource initializes KeySource from the given JWKS json.
+// It's useful for testing without network access.
+func NewStaticKeySource(jwksJSON []byte) (KeySource, error) {
+	keys, err := parseJWKS(jwksJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeySource{keys: keys}, nil
+}
+
+func (s *staticKeySource) PublicKey(_ context.Context, keyID string) (*rsa.PublicKey, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+// minRefetchInterval is the minimum interval to re-fetch JWKS,
+// since the re-fetch can be triggered by unauthenticated requests with unknown key ids.
+const minRefetchInterval = 10 * time.Second
+
+// fetchTimeout is the timeout to fetch JWKS, which is not bound to the request triggering it
+// since the other requests share the result.
+const fetchTimeout = 10 * time.Second
+
+type remoteKeySource struct {
+	url                string
+	httpClient         *http.Client
+	ttl                time.Duration
+	minRefetchInterval time.Duration
+
+	mu               sync.Mutex
+	keys             map[string]*rsa.PublicKey
+	fetchedAt        time.Time
+	fetchAttemptedAt time.Time
+	fetchErr         error
+	// fetching is closed when the in-progress fetch finishes, nil when not fetching.
+	fetching chan struct{}
+}
+
+// NewRemoteKeySource initializes KeySource fetching JWKS from the given url.
+// The fetched keys are cached for ttl and re-fetched when the key id is not found in the cache,
+// at most once in 10 seconds. Concurrent requests share the single fetch.
+// When re-fetching failed, the expired keys in the cache are used.
+func NewRemoteKeySource(url string, httpClient *http.Client, ttl time.Duration) KeySource {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &remoteKeySource{
+		url:                url,
+		httpClient:         httpClient,
+		ttl:                ttl,
+		minRefetchInterval: minRefetchInterval,
+	}
+}
+
+func (r *remoteKeySource) PublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	r.mu.Lock()
+	key, ok := r.keys[keyID]
+	if ok && time.Since(r.fetchedAt) < r.ttl {
+		r.mu.Unlock()
+		return key, nil
+	}
+	fetching := r.fetching
+	if fetching == nil {
+		if time.Since(r.fetchAttemptedAt) < r.minRefetchInterval {
+			defer r.mu.Unlock()
+			return r.cachedKey(keyID)
+		}
+		fetching = make(chan struct{})
+		r.fetching = fetching
+		r.fetchAttemptedAt = time.Now()
+		go r.fetch(context.WithoutCancel(ctx), fetching)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cachedKey(keyID)
+}
+
+// cachedKey returns the cached key even if it's expired, since the last fetch may have failed temporarily.
+// When the key is not cached, the error of the last fetch is returned if any.
+// r.mu must be held by the caller.
+func (r *remoteKeySource) cachedKey(keyID string) (*rsa.PublicKey, error) {
+	if key, ok := r.keys[keyID]; ok {
+		return key, nil
+	}
+	if r.fetchErr != nil {
+		return nil, r.fetchErr
+	}
+	return nil, fmt.Errorf("%w: '%s'", ErrKeyNotFound, keyID)
+}
+
+// fetch fetches JWKS and closes done when finished.
+func (r *remoteKeySource) fetch(ctx context.Context, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	keys, err := r.fetchKeys(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetchErr = err
+	if err == nil {
+		r.keys = keys
+		r.fetchedAt = time.Now()
+	}
+	r.fetching = nil
+	close(done)
+}
+
+func (r *remoteKeySource) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks failed with status %d", resp.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("fetch jwks failed: %w", err)
+	}
+	return parseJWKS(raw)
+}
@@ -0,0 +1,51 @@
+package pm_pushauth
+
+import (
+	"time"
+)
+
+// DefaultIssuers are the issuers of the token attached by Pub/Sub push subscription.
+var DefaultIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+
+type options struct {
+	audience            string
+	issuers             []string
+	serviceAccountEmail string
+	clockSkew           time.Duration
+	now                 func() time.Time
+}
+
+type Option func(*options)
+
+// WithAudience sets the expected audience of the token, which is required.
+// It must be the same as the audience configured in the push subscription,
+// which defaults to the push endpoint url.
+func WithAudience(audience string) Option {
+	return func(o *options) {
+		o.audience = audience
+	}
+}
+
+// WithIssuers overwrites the allowed issuers of the token.
+// Defaults to DefaultIssuers.
+func WithIssuers(issuers ...string) Option {
+	return func(o *options) {
+		o.issuers = issuers
+	}
+}
+
+// WithServiceAccountEmail sets the expected service account email configured in the push subscription,
+// which is required.
+func WithServiceAccountEmail(email string) Option {
+	return func(o *options) {
+		o.serviceAccountEmail = email
+	}
+}
+
+// WithClockSkew sets the allowed clock skew when validating expiry of the token.
+// Defaults to 0.
+func WithClockSkew(d time.Duration) Option {
+	return func(o *options) {
+		o.clockSkew = d
+	}
+}
@@ -0,0 +1,165 @@
+package pm_pushauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Claims represents the claims of the token attached by Pub/Sub push subscription.
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Audience      audience `json:"aud"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Subject       string   `json:"sub"`
+}
+
+// audience can be either a string or an array of strings in JWT.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Middleware returns http middleware which verifies the OIDC token attached to the push request
+// in `Authorization: Bearer` header. The requests with invalid token are rejected with 401
+// before reaching the next handler, e.g. Subscriber.PushHandler.
+// The audience and the service account email must be set by WithAudience and WithServiceAccountEmail,
+// since anyone can get the token signed by Google for any audience, so Middleware panics without them.
+//
+//	keySource := pm_pushauth.NewRemoteKeySource(pm_pushauth.GoogleJWKSURL, nil, time.Hour)
+//	http.Handle("/push", pm_pushauth.Middleware(
+//		keySource,
+//		pm_pushauth.WithAudience("https://example.com/push"),
+//		pm_pushauth.WithServiceAccountEmail("push@project.iam.gserviceaccount.com"),
+//	)(pubsubSubscriber.PushHandler()))
+func Middleware(keySource KeySource, opt ...Option) func(http.Handler) http.Handler {
+	opts := options{
+		issuers: DefaultIssuers,
+		now:     time.Now,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	if opts.audience == "" {
+		panic("pm_pushauth: audience must be set by WithAudience")
+	}
+	if opts.serviceAccountEmail == "" {
+		panic("pm_pushauth: service account email must be set by WithServiceAccountEmail")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || token == r.Header.Get("Authorization") {
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+			if _, err := verify(r.Context(), keySource, &opts, token); err != nil {
+				log.Printf("verify push request token failed: %v\n", err)
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func verify(ctx context.Context, keySource KeySource, opts *options, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("decode header failed: %w", err)
+	}
+	if h.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm '%s'", h.Alg)
+	}
+	key, err := keySource.PublicKey(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature failed: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("verify signature failed: %w", err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("decode claims failed: %w", err)
+	}
+	if err := validateClaims(&claims, opts); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func validateClaims(claims *Claims, opts *options) error {
+	now := opts.now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(opts.clockSkew)) {
+		return errors.New("token is expired")
+	}
+	if !contains(opts.issuers, claims.Issuer) {
+		return fmt.Errorf("unexpected issuer '%s'", claims.Issuer)
+	}
+	if !contains(claims.Audience, opts.audience) {
+		return fmt.Errorf("unexpected audience '%v'", []string(claims.Audience))
+	}
+	if claims.Email != opts.serviceAccountEmail {
+		return fmt.Errorf("unexpected email '%s'", claims.Email)
+	}
+	if !claims.EmailVerified {
+		return fmt.Errorf("email '%s' is not verified", claims.Email)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
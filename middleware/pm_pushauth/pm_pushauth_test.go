@@ -0,0 +1,304 @@
+package pm_pushauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testKeyID    = "test-key"
+	testAudience = "https://example.com/push"
+	testEmail    = "push@test.iam.gserviceaccount.com"
+)
+
+func newTestKeySource(t *testing.T, key *rsa.PrivateKey) KeySource {
+	t.Helper()
+
+	jwksJSON := fmt.Sprintf(
+		`{"keys":[{"kty":"RSA","alg":"RS256","use":"sig","kid":"%s","n":"%s","e":"%s"}]}`,
+		testKeyID,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	)
+	keySource, err := NewStaticKeySource([]byte(jwksJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keySource
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySource := newTestKeySource(t, key)
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":            "https://accounts.google.com",
+			"aud":            testAudience,
+			"email":          testEmail,
+			"email_verified": true,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+		}
+	}
+	withClaim := func(k string, v interface{}) map[string]interface{} {
+		claims := validClaims()
+		claims[k] = v
+		return claims
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantCode      int
+	}{
+		{
+			name:          "passes the request with valid token",
+			authorization: "Bearer " + signToken(t, key, testKeyID, validClaims()),
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:          "rejects the request without token",
+			authorization: "",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the request with non bearer token",
+			authorization: "Basic dGVzdA==",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the token signed by unknown key",
+			authorization: "Bearer " + signToken(t, otherKey, testKeyID, validClaims()),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the token with unknown key id",
+			authorization: "Bearer " + signToken(t, key, "unknown", validClaims()),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the expired token",
+			authorization: "Bearer " + signToken(t, key, testKeyID, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the token with unexpected audience",
+			authorization: "Bearer " + signToken(t, key, testKeyID, withClaim("aud", "https://example.com/other")),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the token with unexpected issuer",
+			authorization: "Bearer " + signToken(t, key, testKeyID, withClaim("iss", "https://example.com")),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the token with unexpected email",
+			authorization: "Bearer " + signToken(t, key, testKeyID, withClaim("email", "other@test.iam.gserviceaccount.com")),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the token with unverified email",
+			authorization: "Bearer " + signToken(t, key, testKeyID, withClaim("email_verified", false)),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects the malformed token",
+			authorization: "Bearer invalid",
+			wantCode:      http.StatusUnauthorized,
+		},
+	}
+
+	middleware := Middleware(keySource, WithAudience(testAudience), WithServiceAccountEmail(testEmail))
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var called bool
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/push", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Middleware() responds %v, want %v", rec.Code, tt.wantCode)
+			}
+			if wantCalled := tt.wantCode == http.StatusOK; called != wantCalled {
+				t.Errorf("Middleware() called next handler: %v, want %v", called, wantCalled)
+			}
+		})
+	}
+}
+
+func TestMiddleware_withoutRequiredOption(t *testing.T) {
+	t.Parallel()
+
+	keySource, err := NewStaticKeySource([]byte(`{"keys":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opt  []Option
+	}{
+		{
+			name: "without audience",
+			opt:  []Option{WithServiceAccountEmail(testEmail)},
+		},
+		{
+			name: "without service account email",
+			opt:  []Option{WithAudience(testAudience)},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Middleware() is expected to panic %s", tt.name)
+				}
+			}()
+			Middleware(keySource, tt.opt...)
+		})
+	}
+}
+
+func TestNewRemoteKeySource(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetchCount int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount++
+		fmt.Fprintf(
+			w,
+			`{"keys":[{"kty":"RSA","kid":"%s","n":"%s","e":"AQAB"}]}`,
+			testKeyID,
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		)
+	}))
+	defer server.Close()
+
+	keySource := NewRemoteKeySource(server.URL, server.Client(), time.Hour)
+	for i := 0; i < 2; i++ {
+		got, err := keySource.PublicKey(context.Background(), testKeyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(&key.PublicKey) {
+			t.Errorf("PublicKey() returned different key")
+		}
+	}
+	if fetchCount != 1 {
+		t.Errorf("JWKS is fetched %d times, want %d", fetchCount, 1)
+	}
+
+	// unknown key ids don't trigger re-fetching within the min refetch interval
+	for i := 0; i < 10; i++ {
+		if _, err := keySource.PublicKey(context.Background(), "unknown"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("PublicKey() error = %v, want %v", err, ErrKeyNotFound)
+		}
+	}
+	if fetchCount != 1 {
+		t.Errorf("JWKS is fetched %d times, want %d", fetchCount, 1)
+	}
+}
+
+func TestNewRemoteKeySource_staleKeys(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(
+			w,
+			`{"keys":[{"kty":"RSA","kid":"%s","n":"%s","e":"AQAB"}]}`,
+			testKeyID,
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		)
+	}))
+	defer server.Close()
+
+	keySource := NewRemoteKeySource(server.URL, server.Client(), time.Millisecond).(*remoteKeySource)
+	keySource.minRefetchInterval = 0
+	if _, err := keySource.PublicKey(context.Background(), testKeyID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the expired key is used when re-fetching failed
+	fail = true
+	time.Sleep(10 * time.Millisecond)
+	got, err := keySource.PublicKey(context.Background(), testKeyID)
+	if err != nil {
+		t.Fatalf("PublicKey() error = %v, want the stale key", err)
+	}
+	if !got.Equal(&key.PublicKey) {
+		t.Errorf("PublicKey() returned different key")
+	}
+	if _, err := keySource.PublicKey(context.Background(), "unknown"); err == nil || errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("PublicKey() error = %v, want the fetch error", err)
+	}
+}
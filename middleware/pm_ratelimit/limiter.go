@@ -0,0 +1,122 @@
+package pm_ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// bucketEvictionInterval is the interval to evict the idle buckets.
+const bucketEvictionInterval = time.Minute
+
+// Limiter is a token bucket rate limiter holding a bucket for each key.
+// The limit and the burst are shared among the buckets and can be adjusted at runtime.
+// The buckets which are full of tokens are evicted periodically, since they are the same as the new ones,
+// so that the buckets for the keys no longer used such as the tenant ids don't grow without bound.
+type Limiter struct {
+	mu            sync.Mutex
+	limit         rate.Limit
+	burst         int
+	limiters      map[string]*rate.Limiter
+	lastEvictedAt time.Time
+	// changed is closed when the limit or the burst is changed.
+	changed chan struct{}
+}
+
+// NewLimiter initializes Limiter allowing perSecond events per second with bursts of at most burst events for each key.
+// burst must be at least 1, otherwise no event is allowed, so NewLimiter panics.
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	validateBurst(burst)
+	return &Limiter{
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		limiters: map[string]*rate.Limiter{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// SetLimit changes the events allowed per second for all keys.
+func (l *Limiter) SetLimit(perSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = rate.Limit(perSecond)
+	for _, limiter := range l.limiters {
+		limiter.SetLimit(l.limit)
+		// rate.Limiter consumes the burst itself while the limit is 0, so it's restored
+		limiter.SetBurst(l.burst)
+	}
+	l.notifyChanged()
+}
+
+// SetBurst changes the max burst size for all keys.
+// burst must be at least 1 as well as NewLimiter.
+func (l *Limiter) SetBurst(burst int) {
+	validateBurst(burst)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst = burst
+	for _, limiter := range l.limiters {
+		limiter.SetBurst(burst)
+	}
+	l.notifyChanged()
+}
+
+// notifyChanged notifies the change of the limit or the burst to the waiters.
+// l.mu must be held by the caller.
+func (l *Limiter) notifyChanged() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// changedSignal returns the channel which is closed when the limit or the burst is changed.
+func (l *Limiter) changedSignal() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.changed
+}
+
+// Limit returns the events allowed per second.
+func (l *Limiter) Limit() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return float64(l.limit)
+}
+
+// Burst returns the max burst size.
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+// bucket returns the token bucket for the key.
+func (l *Limiter) bucket(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastEvictedAt) > bucketEvictionInterval {
+		for k, limiter := range l.limiters {
+			// the burst is consumed instead of the tokens while the limit is 0
+			if limiter.Burst() == l.burst && limiter.TokensAt(now) >= float64(l.burst) {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastEvictedAt = now
+	}
+
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = limiter
+	}
+	return limiter
+}
+
+func validateBurst(burst int) {
+	if burst < 1 {
+		panic(fmt.Sprintf("pm_ratelimit: burst must be at least 1, got %d", burst))
+	}
+}
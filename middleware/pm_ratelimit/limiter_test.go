@@ -0,0 +1,72 @@
+package pm_ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_SetLimit(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(1, 1)
+	bucket := l.bucket("key")
+
+	l.SetLimit(10)
+	l.SetBurst(5)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Limit() = %v, want %v", got, 10)
+	}
+	if got := l.Burst(); got != 5 {
+		t.Errorf("Burst() = %v, want %v", got, 5)
+	}
+	if got := float64(bucket.Limit()); got != 10 {
+		t.Errorf("limit of the existing bucket = %v, want %v", got, 10)
+	}
+	if got := bucket.Burst(); got != 5 {
+		t.Errorf("burst of the existing bucket = %v, want %v", got, 5)
+	}
+	if got := float64(l.bucket("new").Limit()); got != 10 {
+		t.Errorf("limit of the new bucket = %v, want %v", got, 10)
+	}
+}
+
+func TestLimiter_bucket(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(1, 1)
+	if l.bucket("a") != l.bucket("a") {
+		t.Error("bucket() is expected to return the same bucket for the same key")
+	}
+	if l.bucket("a") == l.bucket("b") {
+		t.Error("bucket() is expected to return the different bucket for the different key")
+	}
+}
+
+func TestLimiter_bucket_eviction(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(1, 1)
+	l.bucket("idle")
+	l.bucket("used").Allow()
+
+	// the bucket full of tokens is evicted, while the used one is kept
+	l.lastEvictedAt = time.Time{}
+	l.bucket("new")
+	if _, ok := l.limiters["idle"]; ok {
+		t.Error("idle bucket is expected to be evicted")
+	}
+	if _, ok := l.limiters["used"]; !ok {
+		t.Error("used bucket is expected to be kept")
+	}
+}
+
+func TestNewLimiter_invalidBurst(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewLimiter() is expected to panic with burst 0")
+		}
+	}()
+	NewLimiter(1, 0)
+}
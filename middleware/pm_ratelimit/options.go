@@ -0,0 +1,33 @@
+package pm_ratelimit
+
+type options struct {
+	keyAttribute string
+	limiters     map[string]*Limiter
+	nackOnLimit  bool
+}
+
+type Option func(*options)
+
+// WithKeyAttribute limits the rate for each value of the attribute such as tenant id
+// in addition to each subscription or topic.
+// The messages without the attribute share the same bucket.
+func WithKeyAttribute(attribute string) Option {
+	return func(o *options) {
+		o.keyAttribute = attribute
+	}
+}
+
+// WithLimiterFor overwrites the limiter for the subscription or topic with the given id.
+func WithLimiterFor(id string, limiter *Limiter) Option {
+	return func(o *options) {
+		o.limiters[id] = limiter
+	}
+}
+
+// WithNackOnLimit nacks the message immediately when the rate exceeds the limit instead of waiting.
+// It's ignored by PublishInterceptor since publishing can't be rejected without publishing.
+func WithNackOnLimit() Option {
+	return func(o *options) {
+		o.nackOnLimit = true
+	}
+}
@@ -0,0 +1,97 @@
+package pm_ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// ErrLimited is returned when the message is nacked since the rate exceeds the limit.
+var ErrLimited = errors.New("rate limit exceeded")
+
+// SubscriptionInterceptor limits the rate of the message processing for each subscription by token bucket.
+// By default, the processing waits until the token is available,
+// and the error of the context is returned if the context is done while waiting.
+// With WithNackOnLimit, the message is nacked and ErrLimited marked by pm.Nack is returned instead.
+func SubscriptionInterceptor(limiter *Limiter, opt ...Option) pm.SubscriptionInterceptor {
+	opts := newOptions(opt)
+	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			bucket := opts.limiterFor(info.SubscriptionID, limiter).bucket(opts.key(info.SubscriptionID, m))
+			if opts.nackOnLimit {
+				if !bucket.Allow() {
+					m.Nack()
+					return pm.Nack(ErrLimited)
+				}
+			} else if err := bucket.Wait(ctx); err != nil {
+				return err
+			}
+			return next(ctx, m)
+		}
+	}
+}
+
+// PublishInterceptor limits the rate of the publishing for each topic by token bucket.
+// The publishing waits until the token is available or the context is done. Unlike rate.Limiter.Wait,
+// it doesn't give up waiting in advance when the wait exceeds the deadline of the context.
+// Since the publishing can't be aborted by the interceptor, the message is published when the context is done
+// as well as the underlying topic does, but the token is still consumed to keep the rate in the long run.
+// When the limit is 0, it waits until the limit is raised by Limiter.SetLimit or the context is done.
+// WithNackOnLimit is ignored.
+func PublishInterceptor(limiter *Limiter, opt ...Option) pm.PublishInterceptor {
+	opts := newOptions(opt)
+	return func(next pm.MessagePublisher) pm.MessagePublisher {
+		return func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+			waitToken(ctx, opts.limiterFor(topic.ID(), limiter), opts.key(topic.ID(), m))
+			return next(ctx, topic, m)
+		}
+	}
+}
+
+// waitToken reserves the token of the key's bucket and waits until it's available or ctx is done.
+func waitToken(ctx context.Context, limiter *Limiter, key string) {
+	for {
+		changed := limiter.changedSignal()
+		r := limiter.bucket(key).Reserve()
+		if r.OK() {
+			timer := time.NewTimer(r.Delay())
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+			return
+		}
+		// no token is available with the limit 0 until the limit is changed
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func newOptions(opt []Option) *options {
+	opts := options{limiters: map[string]*Limiter{}}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return &opts
+}
+
+func (o *options) limiterFor(id string, defaultLimiter *Limiter) *Limiter {
+	if limiter, ok := o.limiters[id]; ok {
+		return limiter
+	}
+	return defaultLimiter
+}
+
+func (o *options) key(id string, m *pubsub.Message) string {
+	if o.keyAttribute == "" {
+		return id
+	}
+	return id + "/" + m.Attributes[o.keyAttribute]
+}
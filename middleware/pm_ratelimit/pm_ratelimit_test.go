@@ -0,0 +1,220 @@
+package pm_ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	noop := func(ctx context.Context, m *pubsub.Message) error { return nil }
+	newMessage := func(tenant string) *pubsub.Message {
+		return &pubsub.Message{Attributes: map[string]string{"tenant": tenant}}
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		calls    []*pubsub.Message
+		subs     []string
+		wantErrs []error
+	}{
+		{
+			name:     "nacks over the limit",
+			opts:     []Option{WithNackOnLimit()},
+			calls:    []*pubsub.Message{newMessage("a"), newMessage("a")},
+			subs:     []string{"sub", "sub"},
+			wantErrs: []error{nil, ErrLimited},
+		},
+		{
+			name:     "limits for each subscription",
+			opts:     []Option{WithNackOnLimit()},
+			calls:    []*pubsub.Message{newMessage("a"), newMessage("a")},
+			subs:     []string{"sub1", "sub2"},
+			wantErrs: []error{nil, nil},
+		},
+		{
+			name:     "limits for each key attribute",
+			opts:     []Option{WithNackOnLimit(), WithKeyAttribute("tenant")},
+			calls:    []*pubsub.Message{newMessage("a"), newMessage("b"), newMessage("a")},
+			subs:     []string{"sub", "sub", "sub"},
+			wantErrs: []error{nil, nil, ErrLimited},
+		},
+		{
+			name:     "uses the limiter for the subscription",
+			opts:     []Option{WithNackOnLimit(), WithLimiterFor("sub", NewLimiter(0.001, 2))},
+			calls:    []*pubsub.Message{newMessage("a"), newMessage("a"), newMessage("a")},
+			subs:     []string{"sub", "sub", "sub"},
+			wantErrs: []error{nil, nil, ErrLimited},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			interceptor := SubscriptionInterceptor(NewLimiter(0.001, 1), tt.opts...)
+			for i, m := range tt.calls {
+				err := interceptor(&pm.SubscriptionInfo{SubscriptionID: tt.subs[i]}, noop)(context.Background(), m)
+				if !errors.Is(err, tt.wantErrs[i]) {
+					t.Errorf("call %d: error = %v, want %v", i, err, tt.wantErrs[i])
+				}
+				if tt.wantErrs[i] != nil {
+					if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionNack {
+						t.Errorf("call %d: AckDecisionOf() = %v, want %v", i, decision, pm.AckDecisionNack)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionInterceptor_wait(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewLimiter(0.001, 1)
+	handler := SubscriptionInterceptor(limiter)(&pm.SubscriptionInfo{SubscriptionID: "sub"}, func(ctx context.Context, m *pubsub.Message) error {
+		return nil
+	})
+	if err := handler(context.Background(), &pubsub.Message{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := handler(ctx, &pubsub.Message{}); err == nil {
+		t.Error("error is expected to be returned when the context is done while waiting")
+	}
+
+	// the limit adjusted at runtime is applied
+	limiter.SetLimit(1000)
+	if err := handler(context.Background(), &pubsub.Message{}); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+}
+
+func TestPublishInterceptor(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published := 0
+	publisher := PublishInterceptor(NewLimiter(100, 1))(func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+		published++
+		return nil
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		publisher(context.Background(), pubsubClient.Topic("topic"), &pubsub.Message{})
+	}
+	if published != 3 {
+		t.Errorf("published %v messages, want %v", published, 3)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("publishing is expected to be limited, elapsed: %v", elapsed)
+	}
+}
+
+func TestPublishInterceptor_withDeadline(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := PublishInterceptor(NewLimiter(20, 1))(func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+		return nil
+	})
+
+	// the deadline shorter than the total wait doesn't skip waiting in advance
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		publisher(ctx, pubsubClient.Topic("topic"), &pubsub.Message{})
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("publishing is expected to wait until the deadline, elapsed: %v", elapsed)
+	}
+}
+
+func TestPublishInterceptor_canceled(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		perSecond float64
+	}{
+		{name: "long wait", perSecond: 0.001},
+		{name: "zero limit", perSecond: 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			published := 0
+			publisher := PublishInterceptor(NewLimiter(tt.perSecond, 1))(func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+				published++
+				return nil
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			start := time.Now()
+			for i := 0; i < 2; i++ {
+				publisher(ctx, pubsubClient.Topic("topic"), &pubsub.Message{})
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("publishing is expected to stop waiting when the context is canceled, elapsed: %v", elapsed)
+			}
+			if published != 2 {
+				t.Errorf("published %v messages, want %v", published, 2)
+			}
+		})
+	}
+}
+
+func TestPublishInterceptor_zeroLimitRaised(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := NewLimiter(0, 1)
+	publisher := PublishInterceptor(limiter)(func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+		return nil
+	})
+
+	time.AfterFunc(50*time.Millisecond, func() { limiter.SetLimit(1000) })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2; i++ {
+			publisher(context.Background(), pubsubClient.Topic("topic"), &pubsub.Message{})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("publishing is expected to resume when the limit is raised")
+	}
+}
@@ -0,0 +1,110 @@
+package pm_retry
+
+import (
+	"math"
+	"time"
+
+	"github.com/k-yomo/pm"
+)
+
+// Backoff represents exponential backoff.
+type Backoff struct {
+	// Initial is the backoff for the first retry.
+	Initial time.Duration
+	// Max is the upper bound of the backoff.
+	Max time.Duration
+	// Multiplier is the factor the backoff is multiplied by for each retry.
+	Multiplier float64
+}
+
+// Duration returns the backoff for the given attempt starting from 1.
+func (b Backoff) Duration(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt-1))
+	if d > float64(b.Max) {
+		return b.Max
+	}
+	return time.Duration(d)
+}
+
+// withDefaults returns the backoff whose zero fields are replaced with the given defaults' values.
+func (b Backoff) withDefaults(defaults Backoff) Backoff {
+	if b.Initial == 0 {
+		b.Initial = defaults.Initial
+	}
+	if b.Max == 0 {
+		b.Max = defaults.Max
+	}
+	if b.Multiplier == 0 {
+		b.Multiplier = defaults.Multiplier
+	}
+	return b
+}
+
+var (
+	// DefaultBackoff is the default backoff between the in-process retries.
+	DefaultBackoff = Backoff{Initial: 100 * time.Millisecond, Max: 5 * time.Second, Multiplier: 2}
+	// DefaultRedeliveryBackoff is the default backoff until the message is redelivered.
+	DefaultRedeliveryBackoff = Backoff{Initial: 10 * time.Second, Max: 10 * time.Minute, Multiplier: 2}
+)
+
+// DefaultMaxAttempts is the default max number of in-process attempts including the first one.
+const DefaultMaxAttempts = 3
+
+// RetryableFunc decides if the error is transient and the processing should be retried.
+type RetryableFunc func(err error) bool
+
+// DefaultRetryable treats all errors as retryable except for the ones with the explicit ack decision.
+// The errors marked by pm.Permanent never succeed by retrying, e.g. pm.DecodeError,
+// and the ones marked by pm.Nack / pm.RetryAfter may have been already nacked by the inner interceptors,
+// or the handler decided when to retry.
+func DefaultRetryable(err error) bool {
+	decision, _ := pm.AckDecisionOf(err)
+	return decision == pm.AckDecisionDefault
+}
+
+type options struct {
+	maxAttempts       int
+	backoff           Backoff
+	redeliveryBackoff Backoff
+	retryable         RetryableFunc
+	// sleep is replaceable for testing
+	sleep func(d time.Duration) <-chan time.Time
+}
+
+type Option func(*options)
+
+// WithMaxAttempts sets the max number of in-process attempts including the first one.
+// Defaults to DefaultMaxAttempts. When 1 is set, the processing is not retried in-process.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the backoff between the in-process retries.
+// Defaults to DefaultBackoff, and the zero fields of the given backoff are replaced with DefaultBackoff's values.
+func WithBackoff(b Backoff) Option {
+	return func(o *options) {
+		o.backoff = b.withDefaults(DefaultBackoff)
+	}
+}
+
+// WithRedeliveryBackoff sets the backoff until the message is redelivered, which is calculated by the delivery attempt.
+// Defaults to DefaultRedeliveryBackoff, and the zero fields of the given backoff are replaced with
+// DefaultRedeliveryBackoff's values.
+func WithRedeliveryBackoff(b Backoff) Option {
+	return func(o *options) {
+		o.redeliveryBackoff = b.withDefaults(DefaultRedeliveryBackoff)
+	}
+}
+
+// WithRetryable sets the function to decide if the error is retryable.
+// Defaults to DefaultRetryable.
+func WithRetryable(f RetryableFunc) Option {
+	return func(o *options) {
+		o.retryable = f
+	}
+}
@@ -0,0 +1,91 @@
+package pm_retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/k-yomo/pm"
+)
+
+func TestBackoff_Duration(t *testing.T) {
+	t.Parallel()
+
+	b := Backoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: time.Second},
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 100, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			t.Parallel()
+			if got := b.Duration(tt.attempt); got != tt.want {
+				t.Errorf("Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff_withDefaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    Backoff
+	}{
+		{
+			name:    "zero fields are replaced with defaults",
+			backoff: Backoff{Initial: time.Second},
+			want:    Backoff{Initial: time.Second, Max: DefaultBackoff.Max, Multiplier: DefaultBackoff.Multiplier},
+		},
+		{
+			name:    "set fields are kept",
+			backoff: Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 3},
+			want:    Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 3},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.backoff.withDefaults(DefaultBackoff); got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not marked error", err: errors.New("error"), want: true},
+		{name: "nack error", err: pm.Nack(errors.New("error")), want: false},
+		{name: "retry after error", err: pm.RetryAfter(errors.New("error"), time.Second), want: false},
+		{name: "permanent error", err: pm.Permanent(errors.New("error")), want: false},
+		{name: "decode error", err: pm.Permanent(&pm.DecodeError{ContentType: pm.ContentTypeJSON, Err: errors.New("error")}), want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := DefaultRetryable(tt.err); got != tt.want {
+				t.Errorf("DefaultRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package pm_retry
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// SubscriptionInterceptor retries the processing in-process with backoff when the retryable error is returned.
+// When the processing still fails after the max attempts, the error is marked by pm.RetryAfter with the backoff
+// calculated from the delivery attempt, so that the message is held and redelivered after the backoff expires.
+// The errors which are not retryable, including the ones marked by pm.Permanent / pm.RetryAfter / pm.Nack
+// by default, are returned as is without retrying.
+//
+// Since the message is settled according to the returned error, pm_autoack must be placed before this interceptor.
+// The message is held with extending its deadline up to MaxExtension of ReceiveSettings,
+// so the redelivery backoff should be shorter than that.
+// Also, the delivery attempt is set only when the subscription has dead letter policy,
+// otherwise the initial redelivery backoff is always used.
+//
+//	pubsubSubscriber := pm.NewSubscriber(
+//		pubsubClient,
+//		pm.WithSubscriptionInterceptor(
+//			pm_autoack.SubscriptionInterceptor(),
+//			pm_retry.SubscriptionInterceptor(),
+//		),
+//	)
+func SubscriptionInterceptor(opt ...Option) pm.SubscriptionInterceptor {
+	opts := options{
+		maxAttempts:       DefaultMaxAttempts,
+		backoff:           DefaultBackoff,
+		redeliveryBackoff: DefaultRedeliveryBackoff,
+		retryable:         DefaultRetryable,
+		sleep:             time.After,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return func(_ *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			var err error
+			for attempt := 1; ; attempt++ {
+				err = next(ctx, m)
+				if err == nil || !opts.retryable(err) {
+					return err
+				}
+				if attempt >= opts.maxAttempts {
+					break
+				}
+				select {
+				case <-opts.sleep(opts.backoff.Duration(attempt)):
+				case <-ctx.Done():
+					return err
+				}
+			}
+
+			// the ack decision explicitly made by the handler is respected
+			if decision, _ := pm.AckDecisionOf(err); decision != pm.AckDecisionDefault {
+				return err
+			}
+			deliveryAttempt := 1
+			if m.DeliveryAttempt != nil {
+				deliveryAttempt = *m.DeliveryAttempt
+			}
+			return pm.RetryAfter(err, opts.redeliveryBackoff.Duration(deliveryAttempt))
+		}
+	}
+}
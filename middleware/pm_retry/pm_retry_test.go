@@ -0,0 +1,153 @@
+package pm_retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	immediately := func(o *options) {
+		o.sleep = func(time.Duration) <-chan time.Time {
+			ch := make(chan time.Time, 1)
+			ch <- time.Now()
+			return ch
+		}
+	}
+	deliveryAttempt := 3
+	transientErr := errors.New("transient")
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		message        *pubsub.Message
+		errs           []error
+		wantCalls      int
+		wantDecision   pm.AckDecision
+		wantDelay      time.Duration
+		wantErrMatched error
+	}{
+		{
+			name:      "succeeds at first",
+			message:   &pubsub.Message{},
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "succeeds after retry",
+			message:   &pubsub.Message{},
+			errs:      []error{transientErr, nil},
+			wantCalls: 2,
+		},
+		{
+			name:           "defers redelivery with backoff from delivery attempt",
+			opts:           []Option{WithRedeliveryBackoff(Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2})},
+			message:        &pubsub.Message{DeliveryAttempt: &deliveryAttempt},
+			errs:           []error{transientErr, transientErr, transientErr},
+			wantCalls:      3,
+			wantDecision:   pm.AckDecisionRetryAfter,
+			wantDelay:      4 * time.Second,
+			wantErrMatched: transientErr,
+		},
+		{
+			name:           "uses initial redelivery backoff without delivery attempt",
+			opts:           []Option{WithMaxAttempts(1)},
+			message:        &pubsub.Message{},
+			errs:           []error{transientErr},
+			wantCalls:      1,
+			wantDecision:   pm.AckDecisionRetryAfter,
+			wantDelay:      DefaultRedeliveryBackoff.Initial,
+			wantErrMatched: transientErr,
+		},
+		{
+			name:           "doesn't retry not retryable error",
+			message:        &pubsub.Message{},
+			errs:           []error{pm.Permanent(transientErr)},
+			wantCalls:      1,
+			wantDecision:   pm.AckDecisionPermanent,
+			wantErrMatched: transientErr,
+		},
+		{
+			name:           "custom retryable is used",
+			opts:           []Option{WithRetryable(func(err error) bool { return false })},
+			message:        &pubsub.Message{},
+			errs:           []error{transientErr},
+			wantCalls:      1,
+			wantDecision:   pm.AckDecisionDefault,
+			wantErrMatched: transientErr,
+		},
+		{
+			name:           "doesn't retry explicit nack",
+			message:        &pubsub.Message{},
+			errs:           []error{pm.Nack(transientErr)},
+			wantCalls:      1,
+			wantDecision:   pm.AckDecisionNack,
+			wantErrMatched: transientErr,
+		},
+		{
+			name:           "doesn't retry and respects explicit retry after",
+			message:        &pubsub.Message{},
+			errs:           []error{pm.RetryAfter(transientErr, time.Second)},
+			wantCalls:      1,
+			wantDecision:   pm.AckDecisionRetryAfter,
+			wantDelay:      time.Second,
+			wantErrMatched: transientErr,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			handler := func(ctx context.Context, m *pubsub.Message) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			}
+			opts := append([]Option{immediately}, tt.opts...)
+			err := SubscriptionInterceptor(opts...)(&pm.SubscriptionInfo{}, handler)(context.Background(), tt.message)
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler is called %v times, want %v", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErrMatched) {
+				t.Errorf("SubscriptionInterceptor() error = %v, want %v", err, tt.wantErrMatched)
+			}
+			decision, delay := pm.AckDecisionOf(err)
+			if decision != tt.wantDecision {
+				t.Errorf("AckDecisionOf() decision = %v, want %v", decision, tt.wantDecision)
+			}
+			if delay != tt.wantDelay {
+				t.Errorf("AckDecisionOf() delay = %v, want %v", delay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestSubscriptionInterceptor_contextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	wantErr := errors.New("error")
+	handler := func(ctx context.Context, m *pubsub.Message) error {
+		calls++
+		return wantErr
+	}
+	err := SubscriptionInterceptor(WithBackoff(Backoff{Initial: time.Hour, Max: time.Hour, Multiplier: 1}))(&pm.SubscriptionInfo{}, handler)(ctx, &pubsub.Message{})
+	if calls != 1 {
+		t.Errorf("handler is called %v times, want %v", calls, 1)
+	}
+	if err != wantErr {
+		t.Errorf("SubscriptionInterceptor() error = %v, want %v", err, wantErr)
+	}
+}
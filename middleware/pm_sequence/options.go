@@ -0,0 +1,61 @@
+package pm_sequence
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Policy defines how the anomaly of the sequence is handled.
+type Policy int
+
+const (
+	// PolicyAlert reports the anomaly by the alert function and processes the message anyway.
+	PolicyAlert Policy = iota
+	// PolicyNack reports the anomaly and nacks the message without processing it.
+	// Note that the gap caused by the sequence issued for the message failed to be published is never filled,
+	// so the messages after it keep being nacked.
+	PolicyNack
+	// PolicyHold holds the message with the gap until the preceding sequences are processed or the hold timeout
+	// passes, then it reports the gap and processes the message, since the gap may never be filled,
+	// e.g. the sequence was issued for the message failed to be published.
+	// The duplicated or regressed messages are acked without processing, since the later sequence is already processed.
+	PolicyHold
+)
+
+// AlertFunc is called when the anomaly of the sequence is detected.
+type AlertFunc func(ctx context.Context, m *pubsub.Message, err *AnomalyError)
+
+type options struct {
+	policy       Policy
+	alert        AlertFunc
+	holdTimeout  time.Duration
+	pollInterval time.Duration
+}
+
+type Option func(*options)
+
+// WithPolicy sets the policy for the anomaly.
+// Defaults to PolicyAlert.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithAlert sets the function called when the anomaly is detected.
+// Defaults to logging the anomaly by the standard logger.
+func WithAlert(f AlertFunc) Option {
+	return func(o *options) {
+		o.alert = f
+	}
+}
+
+// WithHoldTimeout sets the max duration to hold the message with PolicyHold.
+// Defaults to 10 seconds.
+func WithHoldTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.holdTimeout = d
+	}
+}
@@ -0,0 +1,243 @@
+package pm_sequence
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// SequenceAttribute is the attribute the sequence of the ordering key is set to.
+const SequenceAttribute = "pm-sequence"
+
+// AnomalyKind represents the kind of the sequence anomaly.
+type AnomalyKind int
+
+const (
+	// AnomalyGap means some preceding sequences are missing.
+	AnomalyGap AnomalyKind = iota + 1
+	// AnomalyDuplicate means the sequence is the same as the last processed one.
+	AnomalyDuplicate
+	// AnomalyRegression means the sequence is older than the last processed one.
+	AnomalyRegression
+)
+
+func (k AnomalyKind) String() string {
+	switch k {
+	case AnomalyGap:
+		return "gap"
+	case AnomalyDuplicate:
+		return "duplicate"
+	case AnomalyRegression:
+		return "regression"
+	default:
+		return "unknown"
+	}
+}
+
+// AnomalyError represents the anomaly of the sequence.
+type AnomalyError struct {
+	Kind           AnomalyKind
+	SubscriptionID string
+	OrderingKey    string
+	// Last is the last processed sequence.
+	Last int64
+	// Sequence is the sequence of the message.
+	Sequence int64
+}
+
+func (e *AnomalyError) Error() string {
+	return fmt.Sprintf("sequence %s for ordering key '%s': last processed %d, got %d", e.Kind, e.OrderingKey, e.Last, e.Sequence)
+}
+
+// PublishInterceptor sets the sequence issued for each ordering key to SequenceAttribute.
+// The messages without ordering key are published as is, and the message which already has the sequence,
+// e.g. the same message published again on retry, keeps it.
+// When issuing the sequence failed, the message is published without the sequence, and the error is logged.
+// Since the sequence is issued before publishing, the message failed to be published leaves a gap
+// unless it's published again, which is given up after the hold timeout with PolicyHold on the subscriber side.
+// The messages with the same ordering key are published serially in the process from issuing the sequence
+// to passing them to the next publisher, so that they are published in the order of the sequence.
+// The attributes of the message are copied before setting the sequence not to modify the map owned by the caller.
+func PublishInterceptor(sequencer Sequencer) pm.PublishInterceptor {
+	locks := newKeyLocks()
+	return func(next pm.MessagePublisher) pm.MessagePublisher {
+		return func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+			if _, ok := m.Attributes[SequenceAttribute]; ok || m.OrderingKey == "" {
+				return next(ctx, topic, m)
+			}
+			key := topic.ID() + "/" + m.OrderingKey
+			unlock := locks.lock(key)
+			defer unlock()
+
+			sequence, err := sequencer.Next(ctx, key)
+			if err != nil {
+				log.Printf("pm_sequence: issue sequence for ordering key '%s' failed: %v", m.OrderingKey, err)
+				return next(ctx, topic, m)
+			}
+			attrs := make(map[string]string, len(m.Attributes)+1)
+			for k, v := range m.Attributes {
+				attrs[k] = v
+			}
+			attrs[SequenceAttribute] = strconv.FormatInt(sequence, 10)
+			m.Attributes = attrs
+			return next(ctx, topic, m)
+		}
+	}
+}
+
+// SubscriptionInterceptor processes the messages with the same ordering key serially in the process,
+// and checks their sequence set by PublishInterceptor against the last processed one in the store.
+// The gaps, duplicates and regressions are handled according to the policy.
+// The last processed sequence is advanced only when the message is processed successfully.
+// The messages without ordering key or sequence are processed as is.
+func SubscriptionInterceptor(store Store, opt ...Option) pm.SubscriptionInterceptor {
+	opts := options{
+		policy: PolicyAlert,
+		alert: func(ctx context.Context, m *pubsub.Message, err *AnomalyError) {
+			log.Printf("pm_sequence: %v for message '%s' of subscription '%s'", err, m.ID, err.SubscriptionID)
+		},
+		holdTimeout:  10 * time.Second,
+		pollInterval: 50 * time.Millisecond,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	c := &checker{store: store, opts: &opts, locks: newKeyLocks()}
+
+	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			sequence, err := strconv.ParseInt(m.Attributes[SequenceAttribute], 10, 64)
+			if m.OrderingKey == "" || err != nil {
+				return next(ctx, m)
+			}
+			return c.process(ctx, info.SubscriptionID, sequence, m, next)
+		}
+	}
+}
+
+type checker struct {
+	store Store
+	opts  *options
+	locks *keyLocks
+}
+
+func (c *checker) process(ctx context.Context, subscriptionID string, sequence int64, m *pubsub.Message, next pm.MessageHandler) error {
+	key := subscriptionID + "/" + m.OrderingKey
+	holdUntil := time.Now().Add(c.opts.holdTimeout)
+	for {
+		unlock := c.locks.lock(key)
+		last, err := c.store.Last(ctx, key)
+		if err != nil {
+			unlock()
+			return err
+		}
+		anomaly := detect(subscriptionID, m.OrderingKey, last, sequence)
+		if anomaly != nil && c.opts.policy == PolicyHold && anomaly.Kind == AnomalyGap && time.Now().Before(holdUntil) {
+			// release the lock for the preceding sequences to be processed
+			unlock()
+			select {
+			case <-time.After(c.opts.pollInterval):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		err = c.handle(ctx, key, sequence, anomaly, m, next)
+		unlock()
+		return err
+	}
+}
+
+// handle processes the message according to the anomaly, the lock of the key must be held by the caller.
+func (c *checker) handle(ctx context.Context, key string, sequence int64, anomaly *AnomalyError, m *pubsub.Message, next pm.MessageHandler) error {
+	if anomaly != nil {
+		if c.opts.alert != nil {
+			c.opts.alert(ctx, m, anomaly)
+		}
+		switch c.opts.policy {
+		case PolicyNack:
+			m.Nack()
+			return pm.Nack(anomaly)
+		case PolicyHold:
+			// the gap is given up after the hold timeout
+			if anomaly.Kind != AnomalyGap {
+				m.Ack()
+				return pm.Permanent(anomaly)
+			}
+		}
+	}
+
+	if err := next(ctx, m); err != nil {
+		return err
+	}
+	return c.store.Advance(ctx, key, sequence)
+}
+
+func detect(subscriptionID, orderingKey string, last, sequence int64) *AnomalyError {
+	var kind AnomalyKind
+	switch {
+	case sequence == last+1:
+		return nil
+	case last == 0:
+		// the first message seen by the store, e.g. the sequence started before the subscription was created
+		return nil
+	case sequence > last+1:
+		kind = AnomalyGap
+	case sequence == last:
+		kind = AnomalyDuplicate
+	default:
+		kind = AnomalyRegression
+	}
+	return &AnomalyError{
+		Kind:           kind,
+		SubscriptionID: subscriptionID,
+		OrderingKey:    orderingKey,
+		Last:           last,
+		Sequence:       sequence,
+	}
+}
+
+// keyLocks provides the mutex for each key, which is removed when no one holds or waits for it.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{locks: map[string]*keyLock{}}
+}
+
+// lock locks the key and returns the function to unlock it.
+func (k *keyLocks) lock(key string) (unlock func()) {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.waiters++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package pm_sequence
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestPublishInterceptor(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var published []*pubsub.Message
+	publisher := PublishInterceptor(NewMemorySequencer())(func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+		published = append(published, m)
+		return nil
+	})
+
+	topic := pubsubClient.Topic("topic")
+	for _, key := range []string{"a", "a", "b", ""} {
+		publisher(context.Background(), topic, &pubsub.Message{OrderingKey: key})
+	}
+	// the message published again keeps the sequence
+	publisher(context.Background(), topic, published[0])
+	publisher(context.Background(), topic, &pubsub.Message{OrderingKey: "a"})
+
+	want := []string{"1", "2", "1", "", "1", "3"}
+	for i, m := range published {
+		if got := m.Attributes[SequenceAttribute]; got != want[i] {
+			t.Errorf("sequence of message %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	// the attributes owned by the caller are not modified
+	attrs := map[string]string{"key": "value"}
+	publisher(context.Background(), topic, &pubsub.Message{OrderingKey: "c", Attributes: attrs})
+	if _, ok := attrs[SequenceAttribute]; ok {
+		t.Error("attributes passed by the caller are not expected to be modified")
+	}
+}
+
+func TestPublishInterceptor_concurrent(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var published []int64
+	publisher := PublishInterceptor(NewMemorySequencer())(func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+		// the later sequence would overtake the earlier one without serializing
+		sequence, _ := strconv.ParseInt(m.Attributes[SequenceAttribute], 10, 64)
+		time.Sleep(time.Duration(10-sequence%10) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, sequence)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			publisher(context.Background(), pubsubClient.Topic("topic"), &pubsub.Message{OrderingKey: "a"})
+		}()
+	}
+	wg.Wait()
+
+	for i, sequence := range published {
+		if sequence != int64(i+1) {
+			t.Fatalf("messages are published in the order %v, want the order of the sequence", published)
+		}
+	}
+}
+
+func newMessage(orderingKey string, sequence int64) *pubsub.Message {
+	return &pubsub.Message{
+		OrderingKey: orderingKey,
+		Attributes:  map[string]string{SequenceAttribute: strconv.FormatInt(sequence, 10)},
+	}
+}
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	info := &pm.SubscriptionInfo{SubscriptionID: "sub"}
+
+	tests := []struct {
+		name          string
+		policy        Policy
+		last          int64
+		message       *pubsub.Message
+		handlerErr    error
+		wantProcessed bool
+		wantAnomaly   AnomalyKind
+		wantDecision  pm.AckDecision
+		wantLast      int64
+	}{
+		{
+			name:          "in order",
+			last:          1,
+			message:       newMessage("key", 2),
+			wantProcessed: true,
+			wantLast:      2,
+		},
+		{
+			name:          "first sequence seen by the store",
+			message:       newMessage("key", 5),
+			wantProcessed: true,
+			wantLast:      5,
+		},
+		{
+			name:          "without sequence",
+			last:          1,
+			message:       &pubsub.Message{OrderingKey: "key"},
+			wantProcessed: true,
+			wantLast:      1,
+		},
+		{
+			name:          "does not advance when processing failed",
+			last:          1,
+			message:       newMessage("key", 2),
+			handlerErr:    errors.New("error"),
+			wantProcessed: true,
+			wantLast:      1,
+		},
+		{
+			name:          "gap is alerted and processed",
+			policy:        PolicyAlert,
+			last:          1,
+			message:       newMessage("key", 3),
+			wantProcessed: true,
+			wantAnomaly:   AnomalyGap,
+			wantLast:      3,
+		},
+		{
+			name:         "duplicate is nacked",
+			policy:       PolicyNack,
+			last:         2,
+			message:      newMessage("key", 2),
+			wantAnomaly:  AnomalyDuplicate,
+			wantDecision: pm.AckDecisionNack,
+			wantLast:     2,
+		},
+		{
+			name:         "regression is acked and skipped when holding",
+			policy:       PolicyHold,
+			last:         3,
+			message:      newMessage("key", 2),
+			wantAnomaly:  AnomalyRegression,
+			wantDecision: pm.AckDecisionPermanent,
+			wantLast:     3,
+		},
+		{
+			name:          "gap is given up and processed after hold timeout",
+			policy:        PolicyHold,
+			last:          1,
+			message:       newMessage("key", 3),
+			wantProcessed: true,
+			wantAnomaly:   AnomalyGap,
+			wantLast:      3,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := NewMemoryStore()
+			_ = store.Advance(context.Background(), "sub/key", tt.last)
+
+			var gotAnomaly AnomalyKind
+			interceptor := SubscriptionInterceptor(
+				store,
+				WithPolicy(tt.policy),
+				WithHoldTimeout(50*time.Millisecond),
+				WithAlert(func(ctx context.Context, m *pubsub.Message, err *AnomalyError) {
+					gotAnomaly = err.Kind
+				}),
+			)
+			processed := false
+			handler := interceptor(info, func(ctx context.Context, m *pubsub.Message) error {
+				processed = true
+				return tt.handlerErr
+			})
+
+			err := handler(context.Background(), tt.message)
+			if processed != tt.wantProcessed {
+				t.Errorf("processed = %v, want %v", processed, tt.wantProcessed)
+			}
+			if gotAnomaly != tt.wantAnomaly {
+				t.Errorf("anomaly = %v, want %v", gotAnomaly, tt.wantAnomaly)
+			}
+			if tt.wantAnomaly != 0 && !tt.wantProcessed {
+				var anomalyErr *AnomalyError
+				if !errors.As(err, &anomalyErr) {
+					t.Errorf("AnomalyError is expected, got: %v", err)
+				}
+			}
+			if decision, _ := pm.AckDecisionOf(err); decision != tt.wantDecision {
+				t.Errorf("ack decision = %v, want %v", decision, tt.wantDecision)
+			}
+			if last, _ := store.Last(context.Background(), "sub/key"); last != tt.wantLast {
+				t.Errorf("last sequence = %v, want %v", last, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestSubscriptionInterceptor_hold(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	_ = store.Advance(context.Background(), "sub/key", 1)
+
+	var mu sync.Mutex
+	var processed []string
+	handler := SubscriptionInterceptor(store, WithPolicy(PolicyHold))(
+		&pm.SubscriptionInfo{SubscriptionID: "sub"},
+		func(ctx context.Context, m *pubsub.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, m.Attributes[SequenceAttribute])
+			return nil
+		},
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := handler(context.Background(), newMessage("key", 3)); err != nil {
+			t.Errorf("held message is expected to be processed, got err: %v", err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := handler(context.Background(), newMessage("key", 2)); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+	wg.Wait()
+
+	if len(processed) != 2 || processed[0] != "2" || processed[1] != "3" {
+		t.Errorf("messages are expected to be processed in sequence, got: %v", processed)
+	}
+}
@@ -0,0 +1,61 @@
+package pm_sequence
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type redisSequencer struct {
+	redisClient *redis.Client
+	keyPrefix   string
+}
+
+// NewRedisSequencer initializes Sequencer which keeps the sequences in redis,
+// so that the sequences are shared among the publisher processes.
+func NewRedisSequencer(redisClient *redis.Client, keyPrefix string) Sequencer {
+	return &redisSequencer{
+		redisClient: redisClient,
+		keyPrefix:   keyPrefix,
+	}
+}
+
+func (r *redisSequencer) Next(ctx context.Context, key string) (int64, error) {
+	return r.redisClient.Incr(ctx, r.keyPrefix+":"+key).Result()
+}
+
+// advanceScript sets the sequence only when it's greater than the current one.
+var advanceScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local sequence = tonumber(ARGV[1])
+if sequence > current then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+type redisStore struct {
+	redisClient *redis.Client
+	keyPrefix   string
+}
+
+// NewRedisStore initializes Store which keeps the sequences in redis,
+// so that the sequences are shared among the subscriber processes.
+func NewRedisStore(redisClient *redis.Client, keyPrefix string) Store {
+	return &redisStore{
+		redisClient: redisClient,
+		keyPrefix:   keyPrefix,
+	}
+}
+
+func (r *redisStore) Last(ctx context.Context, key string) (int64, error) {
+	sequence, err := r.redisClient.Get(ctx, r.keyPrefix+":"+key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return sequence, err
+}
+
+func (r *redisStore) Advance(ctx context.Context, key string, sequence int64) error {
+	return advanceScript.Run(ctx, r.redisClient, []string{r.keyPrefix + ":" + key}, sequence).Err()
+}
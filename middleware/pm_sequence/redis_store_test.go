@@ -0,0 +1,41 @@
+package pm_sequence
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/xid"
+)
+
+func Test_redisStore(t *testing.T) {
+	t.Parallel()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_URL")})
+	keyPrefix := xid.New().String()
+	sequencer := NewRedisSequencer(redisClient, keyPrefix)
+	store := NewRedisStore(redisClient, keyPrefix)
+
+	for want := int64(1); want <= 2; want++ {
+		got, err := sequencer.Next(context.Background(), "sequencer")
+		if err != nil {
+			t.Fatalf("redisSequencer.Next returned err: %v", err)
+		}
+		if got != want {
+			t.Errorf("redisSequencer.Next = %v, want %v", got, want)
+		}
+	}
+
+	if last, err := store.Last(context.Background(), "store"); err != nil || last != 0 {
+		t.Errorf("redisStore.Last = %v, %v, want 0, nil", last, err)
+	}
+	for _, sequence := range []int64{2, 1} {
+		if err := store.Advance(context.Background(), "store", sequence); err != nil {
+			t.Fatalf("redisStore.Advance returned err: %v", err)
+		}
+	}
+	if last, err := store.Last(context.Background(), "store"); err != nil || last != 2 {
+		t.Errorf("redisStore.Last = %v, %v, want 2, nil", last, err)
+	}
+}
@@ -0,0 +1,63 @@
+package pm_sequence
+
+import (
+	"context"
+	"sync"
+)
+
+// Sequencer issues the sequence for each ordering key when publishing.
+type Sequencer interface {
+	// Next increments the sequence of the key and returns it, which starts from 1.
+	Next(ctx context.Context, key string) (int64, error)
+}
+
+// Store stores the last processed sequence for each ordering key when subscribing.
+type Store interface {
+	// Last returns the last processed sequence of the key, 0 when no sequence is processed yet.
+	Last(ctx context.Context, key string) (int64, error)
+	// Advance sets the last processed sequence of the key when it's greater than the current one.
+	Advance(ctx context.Context, key string, sequence int64) error
+}
+
+type memorySequencer struct {
+	mu        sync.Mutex
+	sequences map[string]int64
+}
+
+// NewMemorySequencer initializes Sequencer which keeps the sequences in memory.
+// Since the sequences are reset on restart, it's suitable only for a single publisher process which lives long.
+func NewMemorySequencer() Sequencer {
+	return &memorySequencer{sequences: map[string]int64{}}
+}
+
+func (m *memorySequencer) Next(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sequences[key]++
+	return m.sequences[key], nil
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	sequences map[string]int64
+}
+
+// NewMemoryStore initializes Store which keeps the sequences in memory.
+func NewMemoryStore() Store {
+	return &memoryStore{sequences: map[string]int64{}}
+}
+
+func (m *memoryStore) Last(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sequences[key], nil
+}
+
+func (m *memoryStore) Advance(_ context.Context, key string, sequence int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sequence > m.sequences[key] {
+		m.sequences[key] = sequence
+	}
+	return nil
+}
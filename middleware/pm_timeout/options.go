@@ -0,0 +1,37 @@
+package pm_timeout
+
+import (
+	"time"
+)
+
+type options struct {
+	timeouts         map[string]time.Duration
+	timeoutAttribute string
+	abandon          bool
+}
+
+type Option func(*options)
+
+// WithTimeoutFor overwrites the timeout for the subscription with the given id.
+func WithTimeoutFor(subscriptionID string, timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeouts[subscriptionID] = timeout
+	}
+}
+
+// WithTimeoutAttribute uses the timeout set to the attribute of the message in the format of time.ParseDuration,
+// e.g. "30s". When the attribute is not set or invalid, the timeout for the subscription is used.
+func WithTimeoutAttribute(attribute string) Option {
+	return func(o *options) {
+		o.timeoutAttribute = attribute
+	}
+}
+
+// WithAbandon returns TimeoutError as soon as the timeout passed without waiting for the handler to return.
+// It's useful for the handler which ignores the context, but the abandoned handler keeps running in background
+// and may ack or nack the message later, which is ignored once the message is settled.
+func WithAbandon() Option {
+	return func(o *options) {
+		o.abandon = true
+	}
+}
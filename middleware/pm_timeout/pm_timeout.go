@@ -0,0 +1,91 @@
+package pm_timeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+// ErrTimeout is matched with TimeoutError by errors.Is.
+var ErrTimeout = errors.New("message processing timed out")
+
+// TimeoutError is returned when the message processing didn't finish within the timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+	// Err is the error returned from the handler, nil when the handler is abandoned.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s after %s", ErrTimeout, e.Timeout)
+	}
+	return fmt.Sprintf("%s after %s: %v", ErrTimeout, e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrTimeout}
+	}
+	return []error{ErrTimeout, e.Err}
+}
+
+// SubscriptionInterceptor sets the timeout to the context passed to the message handler.
+// When the handler failed after the timeout passed, TimeoutError wrapping the handler's error is returned,
+// so that it can be distinguished by errors.Is(err, pm_timeout.ErrTimeout).
+// Since TimeoutError is not marked by pm.Permanent or the others, the message is nacked by pm_autoack.
+func SubscriptionInterceptor(timeout time.Duration, opt ...Option) pm.SubscriptionInterceptor {
+	opts := options{timeouts: map[string]time.Duration{}}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return func(info *pm.SubscriptionInfo, next pm.MessageHandler) pm.MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			d := opts.timeout(info.SubscriptionID, m, timeout)
+			timeoutCtx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			if !opts.abandon {
+				return timeoutError(ctx, timeoutCtx, d, next(timeoutCtx, m))
+			}
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- next(timeoutCtx, m)
+			}()
+			select {
+			case err := <-errCh:
+				return timeoutError(ctx, timeoutCtx, d, err)
+			case <-timeoutCtx.Done():
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return &TimeoutError{Timeout: d}
+			}
+		}
+	}
+}
+
+func (o *options) timeout(subscriptionID string, m *pubsub.Message, defaultTimeout time.Duration) time.Duration {
+	if o.timeoutAttribute != "" {
+		if d, err := time.ParseDuration(m.Attributes[o.timeoutAttribute]); err == nil && d > 0 {
+			return d
+		}
+	}
+	if d, ok := o.timeouts[subscriptionID]; ok {
+		return d
+	}
+	return defaultTimeout
+}
+
+// timeoutError wraps err by TimeoutError when it's returned after the timeout passed.
+func timeoutError(parentCtx, timeoutCtx context.Context, timeout time.Duration, err error) error {
+	if err == nil || parentCtx.Err() != nil || !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return &TimeoutError{Timeout: timeout, Err: err}
+}
@@ -0,0 +1,113 @@
+package pm_timeout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/k-yomo/pm"
+)
+
+func TestSubscriptionInterceptor(t *testing.T) {
+	t.Parallel()
+
+	// respectful waits for the context or the processing time set in the data
+	respectful := func(ctx context.Context, m *pubsub.Message) error {
+		d, _ := time.ParseDuration(string(m.Data))
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	// ignoring ignores the context
+	ignoring := func(ctx context.Context, m *pubsub.Message) error {
+		d, _ := time.ParseDuration(string(m.Data))
+		time.Sleep(d)
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		opts        []Option
+		handler     pm.MessageHandler
+		message     *pubsub.Message
+		wantTimeout bool
+		maxElapsed  time.Duration
+	}{
+		{
+			name:    "finishes within timeout",
+			timeout: time.Second,
+			handler: respectful,
+			message: &pubsub.Message{Data: []byte("1ms")},
+		},
+		{
+			name:        "returns timeout error",
+			timeout:     10 * time.Millisecond,
+			handler:     respectful,
+			message:     &pubsub.Message{Data: []byte("1s")},
+			wantTimeout: true,
+		},
+		{
+			name:        "uses timeout for the subscription",
+			timeout:     time.Second,
+			opts:        []Option{WithTimeoutFor("sub", 10*time.Millisecond)},
+			handler:     respectful,
+			message:     &pubsub.Message{Data: []byte("1s")},
+			wantTimeout: true,
+		},
+		{
+			name:        "uses timeout in the attribute",
+			timeout:     time.Second,
+			opts:        []Option{WithTimeoutAttribute("timeout")},
+			handler:     respectful,
+			message:     &pubsub.Message{Data: []byte("1s"), Attributes: map[string]string{"timeout": "10ms"}},
+			wantTimeout: true,
+		},
+		{
+			name:        "abandons the handler ignoring the context",
+			timeout:     10 * time.Millisecond,
+			opts:        []Option{WithAbandon()},
+			handler:     ignoring,
+			message:     &pubsub.Message{Data: []byte("1s")},
+			wantTimeout: true,
+			maxElapsed:  500 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+			err := SubscriptionInterceptor(tt.timeout, tt.opts...)(&pm.SubscriptionInfo{SubscriptionID: "sub"}, tt.handler)(context.Background(), tt.message)
+			if got := errors.Is(err, ErrTimeout); got != tt.wantTimeout {
+				t.Errorf("errors.Is(err, ErrTimeout) = %v, want %v, err: %v", got, tt.wantTimeout, err)
+			}
+			if tt.maxElapsed > 0 && time.Since(start) > tt.maxElapsed {
+				t.Errorf("elapsed %v, want <= %v", time.Since(start), tt.maxElapsed)
+			}
+			var timeoutErr *TimeoutError
+			if tt.wantTimeout && !errors.As(err, &timeoutErr) {
+				t.Errorf("error is expected to be TimeoutError, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSubscriptionInterceptor_parentCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := SubscriptionInterceptor(time.Second)(&pm.SubscriptionInfo{}, func(ctx context.Context, m *pubsub.Message) error {
+		return ctx.Err()
+	})(ctx, &pubsub.Message{})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,124 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// SchemaVersionAttribute is the attribute key for the schema version of the message data.
+const SchemaVersionAttribute = "schema-version"
+
+type publishValueOptions struct {
+	attributes    map[string]string
+	orderingKey   string
+	schemaVersion string
+}
+
+// PublishValueOption is a option to change the message published by PublishValue.
+type PublishValueOption interface {
+	apply(*publishValueOptions)
+}
+
+type publishValueOptionFunc struct {
+	f func(*publishValueOptions)
+}
+
+func (p *publishValueOptionFunc) apply(po *publishValueOptions) {
+	p.f(po)
+}
+
+func newPublishValueOptionFunc(f func(*publishValueOptions)) *publishValueOptionFunc {
+	return &publishValueOptionFunc{
+		f: f,
+	}
+}
+
+// WithAttributes sets attributes to the message.
+func WithAttributes(attrs map[string]string) PublishValueOption {
+	return newPublishValueOptionFunc(func(po *publishValueOptions) {
+		if po.attributes == nil {
+			po.attributes = make(map[string]string, len(attrs))
+		}
+		for k, v := range attrs {
+			po.attributes[k] = v
+		}
+	})
+}
+
+// WithOrderingKey sets ordering key to the message.
+func WithOrderingKey(orderingKey string) PublishValueOption {
+	return newPublishValueOptionFunc(func(po *publishValueOptions) {
+		po.orderingKey = orderingKey
+	})
+}
+
+// WithSchemaVersion sets the schema version attribute to the message.
+func WithSchemaVersion(version string) PublishValueOption {
+	return newPublishValueOptionFunc(func(po *publishValueOptions) {
+		po.schemaVersion = version
+	})
+}
+
+// PublishValue encodes v with the codec and publishes it with applying middlewares.
+// The content type attribute is set to the codec's content type.
+// When encoding failed, it returns error without publishing.
+func (p *Publisher) PublishValue(ctx context.Context, topic *pubsub.Topic, codec Codec, v interface{}, opt ...PublishValueOption) (*pubsub.PublishResult, error) {
+	opts := publishValueOptions{}
+	for _, o := range opt {
+		o.apply(&opts)
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode message data as '%s' failed: %w", codec.ContentType(), err)
+	}
+	attrs := make(map[string]string, len(opts.attributes)+2)
+	for k, v := range opts.attributes {
+		attrs[k] = v
+	}
+	attrs[ContentTypeAttribute] = codec.ContentType()
+	if opts.schemaVersion != "" {
+		attrs[SchemaVersionAttribute] = opts.schemaVersion
+	}
+
+	return p.Publish(ctx, topic, &pubsub.Message{
+		Data:        data,
+		Attributes:  attrs,
+		OrderingKey: opts.orderingKey,
+	}), nil
+}
+
+// TypedTopic publishes values of T to the topic through Publisher.
+type TypedTopic[T any] struct {
+	publisher *Publisher
+	topic     *pubsub.Topic
+	codec     Codec
+	opts      []PublishValueOption
+}
+
+// NewTypedTopic initializes new TypedTopic.
+// The given options are applied to all the messages published by the TypedTopic.
+func NewTypedTopic[T any](publisher *Publisher, topic *pubsub.Topic, codec Codec, opt ...PublishValueOption) *TypedTopic[T] {
+	return &TypedTopic[T]{
+		publisher: publisher,
+		topic:     topic,
+		codec:     codec,
+		opts:      opt,
+	}
+}
+
+// Publish encodes v and publishes it to the topic.
+// The given options are applied after the options given to NewTypedTopic.
+func (t *TypedTopic[T]) Publish(ctx context.Context, v T, opt ...PublishValueOption) (*pubsub.PublishResult, error) {
+	opts := make([]PublishValueOption, 0, len(t.opts)+len(opt))
+	opts = append(opts, t.opts...)
+	opts = append(opts, opt...)
+	return t.publisher.PublishValue(ctx, t.topic, t.codec, v, opts...)
+}
+
+// Topic returns the underlying topic.
+func (t *TypedTopic[T]) Topic() *pubsub.Topic {
+	return t.topic
+}
@@ -0,0 +1,89 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestTypedTopic_Publish(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestTypedTopic_Publish_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestTypedTopic_Publish_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := NewPublisher(pubsubClient, WithPublishInterceptor(func(next MessagePublisher) MessagePublisher {
+		return func(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message) *pubsub.PublishResult {
+			m.Attributes["intercepted"] = "true"
+			return next(ctx, topic, m)
+		}
+	}))
+	typedTopic := NewTypedTopic[typedPayload](publisher, topic, JSONCodec, WithSchemaVersion("v1"))
+
+	ctx := context.Background()
+	result, err := typedTopic.Publish(ctx, typedPayload{Name: "test"}, WithAttributes(map[string]string{"key": "value"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := result.Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	var received *pubsub.Message
+	err = sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		m.Ack()
+		received = m
+		cancel()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received == nil {
+		t.Fatal("message is not received")
+	}
+
+	if got, want := string(received.Data), `{"name":"test"}`; got != want {
+		t.Errorf("Publish() data = %v, want %v", got, want)
+	}
+	wantAttrs := map[string]string{
+		ContentTypeAttribute:   ContentTypeJSON,
+		SchemaVersionAttribute: "v1",
+		"key":                  "value",
+		"intercepted":          "true",
+	}
+	for k, want := range wantAttrs {
+		if got := received.Attributes[k]; got != want {
+			t.Errorf("Publish() attribute %s = %v, want %v", k, got, want)
+		}
+	}
+}
+
+func TestPublisher_PublishValue(t *testing.T) {
+	t.Parallel()
+
+	publisher := NewPublisher(&pubsub.Client{})
+	if _, err := publisher.PublishValue(context.Background(), &pubsub.Topic{}, ProtobufCodec, "not proto message"); err == nil {
+		t.Error("PublishValue() is expected to return error when encoding failed")
+	}
+}
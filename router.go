@@ -0,0 +1,168 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// ErrRouteNotFound is returned from Router when no handler is registered for the message.
+var ErrRouteNotFound = errors.New("route not found")
+
+// UnknownRoutePolicy defines how Router handles a message which no handler is registered for.
+type UnknownRoutePolicy int
+
+const (
+	// UnknownRouteError returns ErrRouteNotFound without acking / nacking the message.
+	UnknownRouteError UnknownRoutePolicy = iota
+	// UnknownRouteAck acks the message and returns nil.
+	UnknownRouteAck
+	// UnknownRouteNack nacks the message and returns ErrRouteNotFound.
+	UnknownRouteNack
+)
+
+type routerOptions struct {
+	unknownRoutePolicy UnknownRoutePolicy
+}
+
+// RouterOption is a option to change router configuration.
+type RouterOption interface {
+	apply(*routerOptions)
+}
+
+type routerOptionFunc struct {
+	f func(*routerOptions)
+}
+
+func (r *routerOptionFunc) apply(ro *routerOptions) {
+	r.f(ro)
+}
+
+func newRouterOptionFunc(f func(*routerOptions)) *routerOptionFunc {
+	return &routerOptionFunc{
+		f: f,
+	}
+}
+
+// WithUnknownRoutePolicy sets the policy for the messages which no handler is registered for.
+// It's used only when the default handler is not registered.
+// Defaults to UnknownRouteError.
+func WithUnknownRoutePolicy(policy UnknownRoutePolicy) RouterOption {
+	return newRouterOptionFunc(func(ro *routerOptions) {
+		ro.unknownRoutePolicy = policy
+	})
+}
+
+// Router routes messages in a single subscription to the handlers based on the attribute value.
+// Register Router.HandleMessage as MessageHandler like below.
+//
+//	router := pm.NewRouter("event_type")
+//	router.Handle("user_created", userCreatedHandler)
+//	router.Handle("user_deleted", userDeletedHandler)
+//	pubsubSubscriber.HandleSubscriptionFunc(sub, router.HandleMessage)
+type Router struct {
+	mu           sync.RWMutex
+	opts         *routerOptions
+	attributeKey string
+	routes       map[string]*route
+	defaultRoute *route
+}
+
+// route holds the handler and its interceptors, which are chained for each subscription lazily,
+// since the router doesn't know which subscriptions it's registered to until the messages arrive.
+type route struct {
+	handler      MessageHandler
+	interceptors []SubscriptionInterceptor
+
+	mu     sync.Mutex
+	chains map[string]MessageHandler
+}
+
+// NewRouter initializes new Router which routes messages by the given attribute key's value.
+func NewRouter(attributeKey string, opt ...RouterOption) *Router {
+	opts := routerOptions{}
+	for _, o := range opt {
+		o.apply(&opts)
+	}
+	return &Router{
+		opts:         &opts,
+		attributeKey: attributeKey,
+		routes:       map[string]*route{},
+	}
+}
+
+// Handle registers the handler for the messages with the given attribute value.
+// The given interceptors are applied only to the route, inside the subscription's interceptors.
+// They are chained for each subscription the router is registered to on its first message,
+// so that SubscriptionInfo of the subscription is passed to them.
+// If a handler already exists for the attribute value, Handle panics like http.ServeMux.
+func (r *Router) Handle(attributeValue string, handler MessageHandler, interceptors ...SubscriptionInterceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.routes[attributeValue]; ok {
+		panic(fmt.Sprintf("pm: multiple registrations for %s '%s'", r.attributeKey, attributeValue))
+	}
+	r.routes[attributeValue] = newRoute(handler, interceptors)
+}
+
+// HandleDefault registers the handler for the messages which no handler is registered for.
+func (r *Router) HandleDefault(handler MessageHandler, interceptors ...SubscriptionInterceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultRoute = newRoute(handler, interceptors)
+}
+
+// HandleMessage dispatches the message to the handler registered for the attribute value.
+func (r *Router) HandleMessage(ctx context.Context, m *pubsub.Message) error {
+	r.mu.RLock()
+	rt, ok := r.routes[m.Attributes[r.attributeKey]]
+	if !ok {
+		rt = r.defaultRoute
+	}
+	r.mu.RUnlock()
+
+	if rt != nil {
+		return rt.handlerFor(ctx)(ctx, m)
+	}
+
+	switch r.opts.unknownRoutePolicy {
+	case UnknownRouteAck:
+		m.Ack()
+		return nil
+	case UnknownRouteNack:
+		m.Nack()
+	}
+	return fmt.Errorf("%w for %s '%s'", ErrRouteNotFound, r.attributeKey, m.Attributes[r.attributeKey])
+}
+
+func newRoute(handler MessageHandler, interceptors []SubscriptionInterceptor) *route {
+	return &route{
+		handler:      handler,
+		interceptors: interceptors,
+		chains:       map[string]MessageHandler{},
+	}
+}
+
+// handlerFor returns the handler chained with the interceptors for the subscription in the context.
+// When the context is not passed from the Subscriber, the interceptors are chained with empty SubscriptionInfo.
+func (rt *route) handlerFor(ctx context.Context) MessageHandler {
+	info, ok := SubscriptionInfoFromContext(ctx)
+	if !ok {
+		info = &SubscriptionInfo{}
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if handler, ok := rt.chains[info.SubscriptionID]; ok {
+		return handler
+	}
+	handler := rt.handler
+	for i := len(rt.interceptors) - 1; i >= 0; i-- {
+		handler = rt.interceptors[i](info, handler)
+	}
+	rt.chains[info.SubscriptionID] = handler
+	return handler
+}
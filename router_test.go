@@ -0,0 +1,140 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestRouter_HandleMessage(t *testing.T) {
+	t.Parallel()
+
+	newRouter := func(opt ...RouterOption) (*Router, *string) {
+		var handled string
+		router := NewRouter("event_type", opt...)
+		router.Handle("created", func(ctx context.Context, m *pubsub.Message) error {
+			handled = "created:" + m.Attributes["intercepted"]
+			return nil
+		}, func(_ *SubscriptionInfo, next MessageHandler) MessageHandler {
+			return func(ctx context.Context, m *pubsub.Message) error {
+				m.Attributes["intercepted"] = "true"
+				return next(ctx, m)
+			}
+		})
+		router.Handle("deleted", func(ctx context.Context, m *pubsub.Message) error {
+			handled = "deleted"
+			return nil
+		})
+		return router, &handled
+	}
+
+	tests := []struct {
+		name        string
+		router      func() (*Router, *string)
+		attrs       map[string]string
+		wantHandled string
+		wantErr     error
+	}{
+		{
+			name:        "routes to the handler with route interceptors",
+			router:      func() (*Router, *string) { return newRouter() },
+			attrs:       map[string]string{"event_type": "created"},
+			wantHandled: "created:true",
+		},
+		{
+			name:        "routes to the handler without route interceptors",
+			router:      func() (*Router, *string) { return newRouter() },
+			attrs:       map[string]string{"event_type": "deleted"},
+			wantHandled: "deleted",
+		},
+		{
+			name: "routes to the default handler when no handler is registered",
+			router: func() (*Router, *string) {
+				router, handled := newRouter()
+				router.HandleDefault(func(ctx context.Context, m *pubsub.Message) error {
+					*handled = "default"
+					return nil
+				})
+				return router, handled
+			},
+			attrs:       map[string]string{"event_type": "updated"},
+			wantHandled: "default",
+		},
+		{
+			name:    "returns ErrRouteNotFound by default when no handler is registered",
+			router:  func() (*Router, *string) { return newRouter() },
+			attrs:   map[string]string{},
+			wantErr: ErrRouteNotFound,
+		},
+		{
+			name:    "returns ErrRouteNotFound with UnknownRouteNack",
+			router:  func() (*Router, *string) { return newRouter(WithUnknownRoutePolicy(UnknownRouteNack)) },
+			attrs:   map[string]string{"event_type": "updated"},
+			wantErr: ErrRouteNotFound,
+		},
+		{
+			name:    "returns nil with UnknownRouteAck",
+			router:  func() (*Router, *string) { return newRouter(WithUnknownRoutePolicy(UnknownRouteAck)) },
+			attrs:   map[string]string{"event_type": "updated"},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, handled := tt.router()
+			err := router.HandleMessage(context.Background(), &pubsub.Message{Attributes: tt.attrs})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("HandleMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if *handled != tt.wantHandled {
+				t.Errorf("HandleMessage() handled = %v, want %v", *handled, tt.wantHandled)
+			}
+		})
+	}
+}
+
+func TestRouter_Handle(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Handle() is expected to panic on duplicated registration")
+		}
+	}()
+
+	router := NewRouter("event_type")
+	handler := func(ctx context.Context, m *pubsub.Message) error { return nil }
+	router.Handle("created", handler)
+	router.Handle("created", handler)
+}
+
+func TestRouter_HandleMessage_subscriptionInfo(t *testing.T) {
+	t.Parallel()
+
+	var chained []string
+	router := NewRouter("event_type")
+	router.Handle("created", func(ctx context.Context, m *pubsub.Message) error {
+		return nil
+	}, func(info *SubscriptionInfo, next MessageHandler) MessageHandler {
+		chained = append(chained, info.SubscriptionID)
+		return next
+	})
+
+	for _, subscriptionID := range []string{"sub-1", "sub-1", "sub-2"} {
+		m := &pubsub.Message{Attributes: map[string]string{"event_type": "created"}}
+		ctx := withMessageContext(context.Background(), &SubscriptionInfo{SubscriptionID: subscriptionID}, m)
+		if err := router.HandleMessage(ctx, m); err != nil {
+			t.Fatalf("HandleMessage() error = %v", err)
+		}
+	}
+
+	if want := []string{"sub-1", "sub-2"}; fmt.Sprint(chained) != fmt.Sprint(want) {
+		t.Errorf("route interceptors are chained with %v, want %v", chained, want)
+	}
+}
@@ -3,7 +3,8 @@ package pm
 import (
 	"context"
 	"fmt"
-	"log"
+	"sort"
+	"strings"
 	"sync"
 
 	"cloud.google.com/go/pubsub"
@@ -16,13 +17,56 @@ type Subscriber struct {
 	opts                 *subscriberOptions
 	pubsubClient         *pubsub.Client
 	subscriptionHandlers map[string]*subscriptionHandler
+	receivers            map[string]*receiver
+	paused               map[string]struct{}
+	receiveCtx           context.Context
+	running              bool
+	activeReceivers      int
+	handlerCtx           context.Context
 	cancel               context.CancelFunc
+	cancelHandlers       context.CancelFunc
+	releaseWait          context.CancelFunc
+	wg                   sync.WaitGroup
+	runErr               RunError
 }
 
 type subscriptionHandler struct {
-	topicID      string
+	info         *SubscriptionInfo
 	subscription *pubsub.Subscription
 	handleFunc   MessageHandler
+	interceptors []SubscriptionInterceptor
+
+	// the chained handler for push is built once, so that the state of the interceptors is kept among the requests.
+	pushOnce    sync.Once
+	pushInfo    *SubscriptionInfo
+	pushHandler MessageHandler
+}
+
+// RunError is returned from Wait when subscriptions stopped with error.
+// The key is subscription id
+type RunError map[string]error
+
+func (r RunError) Error() string {
+	subscriptionIDs := make([]string, 0, len(r))
+	for subscriptionID := range r {
+		subscriptionIDs = append(subscriptionIDs, subscriptionID)
+	}
+	sort.Strings(subscriptionIDs)
+
+	errStrings := make([]string, 0, len(r))
+	for _, subscriptionID := range subscriptionIDs {
+		errStrings = append(errStrings, fmt.Sprintf("%s for subscription '%s'", r[subscriptionID].Error(), subscriptionID))
+	}
+	return strings.Join(errStrings, ", ")
+}
+
+// Unwrap returns the errors of all the failed subscriptions.
+func (r RunError) Unwrap() []error {
+	errs := make([]error, 0, len(r))
+	for _, err := range r {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
 // MessageHandler defines the message handler invoked by SubscriptionInterceptor to complete the normal
@@ -44,29 +88,68 @@ func NewSubscriber(pubsubClient *pubsub.Client, opt ...SubscriberOption) *Subscr
 
 // HandleSubscriptionFunc registers subscription handler for the given id's subscription.
 // If subscription does not exist, it will return error.
-func (s *Subscriber) HandleSubscriptionFunc(subscription *pubsub.Subscription, f MessageHandler) error {
+// The receive settings of the subscription can be customized by SubscriptionOption,
+// otherwise the ReceiveSettings set to the given subscription will be used.
+// When the subscriber is already running, receiving the subscription starts immediately.
+func (s *Subscriber) HandleSubscriptionFunc(subscription *pubsub.Subscription, f MessageHandler, opt ...SubscriptionOption) error {
 	s.mu.RLock()
-	if _, ok := s.subscriptionHandlers[subscription.ID()]; ok {
+	_, registered := s.subscriptionHandlers[subscription.ID()]
+	s.mu.RUnlock()
+	if registered {
 		return fmt.Errorf("handler for subscription '%s' is already registered", subscription.ID())
 	}
-	s.mu.RUnlock()
 
 	cfg, err := subscription.Config(context.Background())
 	if err != nil {
 		return err
 	}
 
+	opts := subscriptionOptions{receiveSettings: subscription.ReceiveSettings}
+	for _, o := range opt {
+		o.apply(&opts)
+	}
+
 	s.mu.Lock()
-	s.subscriptionHandlers[subscription.ID()] = &subscriptionHandler{
-		topicID:      cfg.Topic.ID(),
+	defer s.mu.Unlock()
+	if _, ok := s.subscriptionHandlers[subscription.ID()]; ok {
+		return fmt.Errorf("handler for subscription '%s' is already registered", subscription.ID())
+	}
+	subscription.ReceiveSettings = opts.receiveSettings
+	h := &subscriptionHandler{
+		info:         newSubscriptionInfo(subscription, cfg),
 		subscription: subscription,
 		handleFunc:   f,
+		interceptors: opts.subscriptionInterceptors,
+	}
+	s.subscriptionHandlers[subscription.ID()] = h
+	if s.running {
+		s.startReceiver(h)
 	}
-	s.mu.Unlock()
 
 	return nil
 }
 
+// Unsubscribe unregisters the handler for the given id's subscription.
+// When the subscription is running, it stops receiving and waits for in-flight messages to be processed
+// in the same manner as Shutdown.
+func (s *Subscriber) Unsubscribe(ctx context.Context, subscriptionID string) (*ShutdownReport, error) {
+	s.mu.Lock()
+	if _, ok := s.subscriptionHandlers[subscriptionID]; !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("handler for subscription '%s' is not registered", subscriptionID)
+	}
+	delete(s.subscriptionHandlers, subscriptionID)
+	delete(s.paused, subscriptionID)
+	r, running := s.receivers[subscriptionID]
+	delete(s.receivers, subscriptionID)
+	s.mu.Unlock()
+
+	if !running {
+		return &ShutdownReport{}, nil
+	}
+	return r.stop(ctx)
+}
+
 // HandleSubscriptionFuncMap registers multiple subscription handlers at once.
 // This function take map of key[subscription id]: value[corresponding message handler] pairs.
 func (s *Subscriber) HandleSubscriptionFuncMap(funcMap map[*pubsub.Subscription]MessageHandler) error {
@@ -81,35 +164,132 @@ func (s *Subscriber) HandleSubscriptionFuncMap(funcMap map[*pubsub.Subscription]
 }
 
 // Run starts running registered pull subscriptions.
+// Run doesn't block, use Wait or RunAndWait to wait until all subscriptions stop.
 func (s *Subscriber) Run(ctx context.Context) {
+	// message handlers keep running with handlerCtx after receiving is stopped by Shutdown,
+	// so that in-flight messages can be drained.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	context.AfterFunc(ctx, cancelHandlers)
 	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receiveCtx = ctx
+	s.handlerCtx = handlerCtx
 	s.cancel = cancel
+	s.cancelHandlers = cancelHandlers
+	s.receivers = map[string]*receiver{}
+	s.runErr = nil
+	s.running = true
+	s.activeReceivers = 0
+
+	// keep Wait blocking until the context is done even when no subscription is running,
+	// so that subscriptions can be registered later, e.g. multi-tenant workers starting with no tenants.
+	// It's released when all the receivers stopped and any of them failed, not to keep the failed subscriber running.
+	waitCtx, releaseWait := context.WithCancel(ctx)
+	s.releaseWait = releaseWait
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		<-waitCtx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running = false
+	}()
+
+	for _, h := range s.subscriptionHandlers {
+		s.startReceiver(h)
+	}
+}
 
-	for subscriptionID, handler := range s.subscriptionHandlers {
-		sub := s.pubsubClient.Subscription(subscriptionID)
-		h := handler
-		subscriptionInfo := SubscriptionInfo{
-			TopicID:        h.topicID,
-			SubscriptionID: h.subscription.ID(),
+// startReceiver starts receiving messages of the given handler's subscription.
+// s.mu must be held by the caller.
+func (s *Subscriber) startReceiver(h *subscriptionHandler) {
+	subscriptionInfo, last := s.chainInterceptors(h)
+	r := newReceiver(s.receiveCtx, s.handlerCtx, subscriptionInfo, h.subscription, last, s.opts.restartPolicy)
+	s.receivers[subscriptionInfo.SubscriptionID] = r
+	if _, ok := s.paused[subscriptionInfo.SubscriptionID]; ok {
+		r.pause()
+	}
+	s.activeReceivers++
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.receiverStopped(subscriptionInfo.SubscriptionID, r.run())
+	}()
+}
+
+// receiverStopped records the error the receiver stopped with.
+func (s *Subscriber) receiverStopped(subscriptionID string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activeReceivers--
+	if err != nil {
+		if s.runErr == nil {
+			s.runErr = RunError{}
+		}
+		s.runErr[subscriptionID] = err
+		if s.opts.stopAllOnError {
+			s.cancel()
 		}
-		go func() {
-			last := h.handleFunc
-			for i := len(s.opts.subscriptionInterceptors) - 1; i >= 0; i-- {
-				last = s.opts.subscriptionInterceptors[i](&subscriptionInfo, last)
-			}
-			err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
-				_ = last(ctx, m)
-			})
-			if err != nil {
-				log.Printf("%+v\n", err)
-			}
-		}()
+	}
+	if s.activeReceivers == 0 && len(s.runErr) > 0 {
+		s.releaseWait()
 	}
 }
 
-// Close closes running subscriptions gracefully.
+// chainInterceptors wraps the handler with the subscriber's interceptors and the subscription's interceptors.
+func (s *Subscriber) chainInterceptors(h *subscriptionHandler) (*SubscriptionInfo, MessageHandler) {
+	// copy not to share the info between the receivers
+	info := *h.info
+	subscriptionInfo := &info
+	last := h.handleFunc
+	for i := len(h.interceptors) - 1; i >= 0; i-- {
+		last = h.interceptors[i](subscriptionInfo, last)
+	}
+	for i := len(s.opts.subscriptionInterceptors) - 1; i >= 0; i-- {
+		last = s.opts.subscriptionInterceptors[i](subscriptionInfo, last)
+	}
+	return subscriptionInfo, last
+}
+
+// Wait blocks until the subscriber stops running, that is, the context passed to Run is done
+// or Close / Shutdown is called, and all the subscriptions stop.
+// It keeps blocking even when no subscription is running, e.g. all of them are unsubscribed,
+// unless any subscription stopped with error.
+// When any subscription stopped with error, it returns RunError.
+func (s *Subscriber) Wait() error {
+	s.wg.Wait()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.runErr) == 0 {
+		return nil
+	}
+	runErr := make(RunError, len(s.runErr))
+	for subscriptionID, err := range s.runErr {
+		runErr[subscriptionID] = err
+	}
+	return runErr
+}
+
+// RunAndWait starts running registered pull subscriptions and blocks until the subscriber stops running.
+// When any subscription stopped with error, it returns RunError.
+func (s *Subscriber) RunAndWait(ctx context.Context) error {
+	s.Run(ctx)
+	return s.Wait()
+}
+
+// Close stops running subscriptions immediately.
+// The context passed to the in-flight message handlers is canceled as well,
+// use Shutdown to wait for them to finish.
 func (s *Subscriber) Close() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.cancel != nil {
 		s.cancel()
 	}
+	if s.cancelHandlers != nil {
+		s.cancelHandlers()
+	}
 }
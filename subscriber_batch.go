@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/pubsub"
@@ -29,8 +30,17 @@ func (b BatchError) Error() string {
 // MessageBatchHandler defines the batch message handler
 // By default, when non-nil error is returned, all messages are processed as error in MessageHandler
 // To handle error for each message, use BatchError
+// The errors can be marked by Permanent, RetryAfter or Nack, which is applied to each message's error
+// even when the whole BatchError is marked.
 type MessageBatchHandler func(messages []*pubsub.Message) error
 
+// ContextMessageBatchHandler defines the batch message handler receiving the context.
+// The context carries the values of the first message's context in the batch, e.g. SubscriptionInfo and the loggers,
+// but not the message specific values such as MessageFromContext, since it's shared among the messages.
+// It's canceled when the contexts of all the messages in the batch are done, e.g. the subscriber is closed.
+// The errors are handled in the same manner as MessageBatchHandler.
+type ContextMessageBatchHandler func(ctx context.Context, messages []*pubsub.Message) error
+
 type BatchMessageHandlerConfig struct {
 	// Process a non-empty batch after this delay has passed.
 	// Defaults to DefaultMessageBatchHandlerConfig.DelayThreshold.
@@ -70,24 +80,44 @@ type messageBatchHandleScheduler struct {
 
 // NewBatchMessageHandler initializes MessageHandler for batch message processing with config
 func NewBatchMessageHandler(handler MessageBatchHandler, config BatchMessageHandlerConfig) MessageHandler {
+	return newMessageBatchHandler(func(_ context.Context, messages []*pubsub.Message) error {
+		return handler(messages)
+	}, config)
+}
+
+// NewContextBatchMessageHandler initializes MessageHandler for batch message processing with config,
+// which passes the context to the handler.
+func NewContextBatchMessageHandler(handler ContextMessageBatchHandler, config BatchMessageHandlerConfig) MessageHandler {
 	return newMessageBatchHandler(handler, config)
 }
 
-func newMessageBatchHandler(handler MessageBatchHandler, config BatchMessageHandlerConfig) MessageHandler {
+func newMessageBatchHandler(handler ContextMessageBatchHandler, config BatchMessageHandlerConfig) MessageHandler {
 	batchScheduler := newMessageBatchHandleScheduler(handler, config)
 	return func(ctx context.Context, msg *pubsub.Message) error {
-		errCh := make(chan error)
-		bm := bundledMessage{msg: msg, err: errCh}
+		// buffered not to block the bundle handler when this handler already returned
+		errCh := make(chan error, 1)
+		bm := bundledMessage{ctx: ctx, msg: msg, err: errCh}
 		if err := batchScheduler.add(&bm); err != nil {
 			return err
 		}
 
-		err := <-errCh
-		return err
+		drainSignal := DrainSignalFromContext(ctx)
+		for {
+			select {
+			case err := <-errCh:
+				return err
+			case <-drainSignal:
+				// the subscriber is shutting down, so process the pending messages without waiting for the thresholds.
+				drainSignal = nil
+				go batchScheduler.flush()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
 	}
 }
 
-func newMessageBatchHandleScheduler(handler MessageBatchHandler, config BatchMessageHandlerConfig) *messageBatchHandleScheduler {
+func newMessageBatchHandleScheduler(handler ContextMessageBatchHandler, config BatchMessageHandlerConfig) *messageBatchHandleScheduler {
 	if config.DelayThreshold == 0 {
 		config.DelayThreshold = DefaultMessageBatchHandlerConfig.DelayThreshold
 	}
@@ -110,6 +140,7 @@ func newMessageBatchHandleScheduler(handler MessageBatchHandler, config BatchMes
 }
 
 type bundledMessage struct {
+	ctx context.Context
 	msg *pubsub.Message
 	err chan<- error
 }
@@ -124,7 +155,11 @@ func (m *messageBatchHandleScheduler) add(bm *bundledMessage) error {
 	return m.bundler.Add(bm, msgSize)
 }
 
-func newBundler(handler MessageBatchHandler, config BatchMessageHandlerConfig) *bundler.Bundler {
+func (m *messageBatchHandleScheduler) flush() {
+	m.bundler.Flush()
+}
+
+func newBundler(handler ContextMessageBatchHandler, config BatchMessageHandlerConfig) *bundler.Bundler {
 	b := bundler.NewBundler(&bundledMessage{}, newBundleHandler(handler))
 	b.HandlerLimit = config.NumGoroutines
 	b.DelayThreshold = config.DelayThreshold
@@ -135,7 +170,7 @@ func newBundler(handler MessageBatchHandler, config BatchMessageHandlerConfig) *
 	return b
 }
 
-func newBundleHandler(handler MessageBatchHandler) func(bundle interface{}) {
+func newBundleHandler(handler ContextMessageBatchHandler) func(bundle interface{}) {
 	return func(bundle interface{}) {
 		bundledMessages := bundle.([]*bundledMessage)
 
@@ -144,12 +179,40 @@ func newBundleHandler(handler MessageBatchHandler) func(bundle interface{}) {
 			messages = append(messages, bm.msg)
 		}
 
-		err := handler(messages)
+		ctx, cancel := newBatchContext(bundledMessages)
+		err := handler(ctx, messages)
+		cancel()
 		var batchErr BatchError
-		if errors.As(err, &batchErr) {
-			for _, bm := range bundledMessages {
-				bm.err <- batchErr[bm.msg.ID]
+		isBatchErr := errors.As(err, &batchErr)
+		for _, bm := range bundledMessages {
+			if isBatchErr {
+				// the ack decision marked to the whole BatchError is applied to each message's error
+				bm.err <- withAckDecisionOf(err, batchErr[bm.msg.ID])
+			} else {
+				bm.err <- err
 			}
 		}
 	}
 }
+
+// newBatchContext returns the context for processing the bundled messages.
+// It carries the values of the first message's context, and it's canceled when the contexts of all the messages are done,
+// since no one waits for the result then.
+func newBatchContext(bundledMessages []*bundledMessage) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(withBatchContext(context.WithoutCancel(bundledMessages[0].ctx)))
+	remaining := int32(len(bundledMessages))
+	stops := make([]func() bool, 0, len(bundledMessages))
+	for _, bm := range bundledMessages {
+		stops = append(stops, context.AfterFunc(bm.ctx, func() {
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				cancel()
+			}
+		}))
+	}
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
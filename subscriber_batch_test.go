@@ -133,17 +133,124 @@ func Test_MessageBatchHandler(t *testing.T) {
 			t.Errorf("Error() = %v, want %v", err, nil)
 		}
 	})
+	t.Run("ack decision of batch error is applied to each message's error", func(t *testing.T) {
+		t.Parallel()
+
+		batchConfig := BatchMessageHandlerConfig{
+			DelayThreshold:    10 * time.Millisecond,
+			CountThreshold:    2,
+			ByteThreshold:     DefaultMessageBatchHandlerConfig.ByteThreshold,
+			NumGoroutines:     1,
+			BufferedByteLimit: DefaultMessageBatchHandlerConfig.BufferedByteLimit,
+		}
+		wantErr := errors.New("error")
+		msgHandler := NewBatchMessageHandler(func(messages []*pubsub.Message) error {
+			return Permanent(BatchError{"1": wantErr})
+		}, batchConfig)
+
+		eg := errgroup.Group{}
+		eg.Go(func() error {
+			err := msgHandler(context.Background(), &pubsub.Message{ID: "1"})
+			if !errors.Is(err, wantErr) {
+				t.Errorf("Error() = %v, want %v", err, wantErr)
+			}
+			if decision, _ := AckDecisionOf(err); decision != AckDecisionPermanent {
+				t.Errorf("AckDecisionOf() = %v, want %v", decision, AckDecisionPermanent)
+			}
+			return nil
+		})
+		eg.Go(func() error {
+			if err := msgHandler(context.Background(), &pubsub.Message{ID: "2"}); err != nil {
+				t.Errorf("Error() = %v, want %v", err, nil)
+			}
+			return nil
+		})
+		if err := eg.Wait(); err != nil {
+			t.Errorf("Error() = %v, want %v", err, nil)
+		}
+	})
+}
+
+func Test_ContextMessageBatchHandler(t *testing.T) {
+	t.Parallel()
+
+	batchConfig := BatchMessageHandlerConfig{
+		DelayThreshold: 10 * time.Millisecond,
+		CountThreshold: 2,
+		NumGoroutines:  1,
+	}
+
+	t.Run("context carries subscription info", func(t *testing.T) {
+		t.Parallel()
+
+		info := &SubscriptionInfo{SubscriptionID: "test-sub"}
+		msgHandler := NewContextBatchMessageHandler(func(ctx context.Context, messages []*pubsub.Message) error {
+			if got, ok := SubscriptionInfoFromContext(ctx); !ok || got != info {
+				t.Errorf("SubscriptionInfoFromContext() = %v, %v, want %v, true", got, ok, info)
+			}
+			if _, ok := MessageFromContext(ctx); ok {
+				t.Error("MessageFromContext() is expected not to return the message")
+			}
+			if _, ok := ProcessingIDFromContext(ctx); !ok {
+				t.Error("ProcessingIDFromContext() is expected to return the processing id")
+			}
+			return BatchError{"1": errors.New("error")}
+		}, batchConfig)
+
+		eg := errgroup.Group{}
+		for _, id := range []string{"1", "2"} {
+			m := &pubsub.Message{ID: id}
+			eg.Go(func() error {
+				return msgHandler(withMessageContext(context.Background(), info, m), m)
+			})
+		}
+		if err := eg.Wait(); err == nil {
+			t.Error("error for message '1' is expected to be returned")
+		}
+	})
+
+	t.Run("context is canceled when contexts of all messages are done", func(t *testing.T) {
+		t.Parallel()
+
+		canceled := make(chan struct{})
+		msgHandler := NewContextBatchMessageHandler(func(ctx context.Context, messages []*pubsub.Message) error {
+			select {
+			case <-ctx.Done():
+				close(canceled)
+			case <-time.After(time.Second):
+			}
+			return ctx.Err()
+		}, batchConfig)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		eg := errgroup.Group{}
+		for i := 0; i < 2; i++ {
+			eg.Go(func() error {
+				return msgHandler(ctx, &pubsub.Message{})
+			})
+		}
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		if err := eg.Wait(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Error() = %v, want %v", err, context.Canceled)
+		}
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Error("context passed to the batch handler is expected to be canceled")
+		}
+	})
 }
 
 func Test_newMessageBatchHandleScheduler(t *testing.T) {
 	t.Parallel()
 
-	testHanlder := func(messages []*pubsub.Message) error {
+	testHanlder := func(ctx context.Context, messages []*pubsub.Message) error {
 		return nil
 	}
 
 	type args struct {
-		handler MessageBatchHandler
+		handler ContextMessageBatchHandler
 		config  BatchMessageHandlerConfig
 	}
 	tests := []struct {
@@ -188,7 +295,7 @@ func Test_newMessageBatchHandleScheduler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got := newMessageBatchHandleScheduler(tt.args.handler, tt.args.config)
-			if diff := cmp.Diff(got.bundler, tt.wantBundler, cmpopts.IgnoreUnexported(*got.bundler)); diff != "" {
+			if diff := cmp.Diff(got.bundler, tt.wantBundler, cmpopts.IgnoreUnexported(bundler.Bundler{})); diff != "" {
 				t.Errorf("newMessageBatchHandleScheduler() = (-want +got):\n%s", diff)
 			}
 		})
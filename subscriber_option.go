@@ -2,6 +2,8 @@ package pm
 
 type subscriberOptions struct {
 	subscriptionInterceptors []SubscriptionInterceptor
+	stopAllOnError           bool
+	restartPolicy            *RestartPolicy
 }
 
 // SubscriberOption is a option to change subscriber configuration.
@@ -29,3 +31,21 @@ func WithSubscriptionInterceptor(interceptors ...SubscriptionInterceptor) Subscr
 		so.subscriptionInterceptors = interceptors
 	})
 }
+
+// WithStopAllOnError makes the subscriber stop all the other subscriptions when one of them fails.
+// By default, the other subscriptions keep running.
+func WithStopAllOnError() SubscriberOption {
+	return newSubscriberOptionFunc(func(so *subscriberOptions) {
+		so.stopAllOnError = true
+	})
+}
+
+// WithRestartPolicy makes the subscriber restart the receiver of a subscription which stopped with error
+// based on the given policy. Zero values of the policy except Jitter are replaced with DefaultRestartPolicy's values,
+// e.g. WithRestartPolicy(pm.RestartPolicy{}) restarts up to DefaultRestartPolicy.MaxRestarts times.
+// By default, the receiver is not restarted.
+func WithRestartPolicy(policy RestartPolicy) SubscriberOption {
+	return newSubscriberOptionFunc(func(so *subscriberOptions) {
+		so.restartPolicy = policy.withDefaults()
+	})
+}
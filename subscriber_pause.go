@@ -0,0 +1,102 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pause stops receiving messages of the given id's subscription until Resume is called.
+// The in-flight messages continue to be processed, and the messages are kept in the subscription while paused.
+// When the subscriber is not running, the subscription starts paused on Run.
+func (s *Subscriber) Pause(subscriptionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscriptionHandlers[subscriptionID]; !ok {
+		return fmt.Errorf("handler for subscription '%s' is not registered", subscriptionID)
+	}
+	if s.paused == nil {
+		s.paused = map[string]struct{}{}
+	}
+	s.paused[subscriptionID] = struct{}{}
+	if r, ok := s.receivers[subscriptionID]; ok {
+		r.pause()
+	}
+	return nil
+}
+
+// Resume restarts receiving messages of the given id's subscription paused by Pause.
+func (s *Subscriber) Resume(subscriptionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscriptionHandlers[subscriptionID]; !ok {
+		return fmt.Errorf("handler for subscription '%s' is not registered", subscriptionID)
+	}
+	delete(s.paused, subscriptionID)
+	if r, ok := s.receivers[subscriptionID]; ok {
+		r.resume()
+	}
+	return nil
+}
+
+// setCancelReceive sets the function to cancel the current receiving, nil when the receiving stopped.
+// It returns false when the receiver is paused and receiving should not be started.
+func (r *receiver) setCancelReceive(cancel context.CancelFunc) bool {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	if cancel != nil && r.resumed != nil {
+		return false
+	}
+	r.cancelReceive = cancel
+	return true
+}
+
+func (r *receiver) pause() {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	if r.resumed != nil {
+		return
+	}
+	r.resumed = make(chan struct{})
+	if r.cancelReceive != nil {
+		r.cancelReceive()
+		r.pausedReceive = true
+	}
+	r.status.setState(ReceiverStatePaused, nil)
+}
+
+func (r *receiver) resume() {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	if r.resumed == nil {
+		return
+	}
+	close(r.resumed)
+	r.resumed = nil
+	r.status.setState(ReceiverStateRunning, nil)
+}
+
+// takePaused reports whether the last receiving stopped by pause, or the receiver is paused.
+// Even when it's already resumed, it returns true if the receiving was canceled by pause,
+// since the receiving may stop after resumed while waiting for the in-flight messages.
+func (r *receiver) takePaused() bool {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	paused := r.pausedReceive || r.resumed != nil
+	r.pausedReceive = false
+	return paused
+}
+
+// waitResumed blocks until the receiver is resumed or stopped when it's paused.
+// It returns immediately when the receiver is not paused.
+func (r *receiver) waitResumed() {
+	r.pauseMu.Lock()
+	resumed := r.resumed
+	r.pauseMu.Unlock()
+	if resumed == nil {
+		return
+	}
+	select {
+	case <-resumed:
+	case <-r.receiveCtx.Done():
+	}
+}
@@ -0,0 +1,177 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestSubscriber_PauseResume(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_PauseResume_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_PauseResume_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := NewSubscriber(pubsubClient)
+	defer subscriber.Close()
+
+	received := make(chan struct{}, 1)
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		received <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := subscriber.Pause("unknown"); err == nil {
+		t.Error("Pause() is expected to return error for not registered subscription")
+	}
+	if err := subscriber.Resume("unknown"); err == nil {
+		t.Error("Resume() is expected to return error for not registered subscription")
+	}
+
+	// the subscription paused before running starts paused
+	if err := subscriber.Pause(sub.ID()); err != nil {
+		t.Fatal(err)
+	}
+	subscriber.Run(context.Background())
+	if got := subscriber.Status()[sub.ID()].State; got != ReceiverStatePaused {
+		t.Errorf("state = %v, want %v", got, ReceiverStatePaused)
+	}
+
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+		t.Fatal("message must not be received while paused")
+	case <-time.After(1 * time.Second):
+	}
+
+	if err := subscriber.Resume(sub.ID()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message is expected to be received after resumed")
+	}
+	if got := subscriber.Status()[sub.ID()].State; got != ReceiverStateRunning {
+		t.Errorf("state = %v, want %v", got, ReceiverStateRunning)
+	}
+
+	// pause while receiving
+	if err := subscriber.Pause(sub.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+		t.Fatal("message must not be received while paused")
+	case <-time.After(1 * time.Second):
+	}
+	if err := subscriber.Resume(sub.ID()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message is expected to be received after resumed")
+	}
+}
+
+func TestSubscriber_PauseResume_withInFlightMessage(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_PauseResume_withInFlightMessage_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_PauseResume_withInFlightMessage_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := NewSubscriber(pubsubClient)
+	defer subscriber.Close()
+
+	received := make(chan string, 2)
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		received <- string(m.Data)
+		if string(m.Data) == "slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		m.Ack()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriber.Run(context.Background())
+
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("slow")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message is expected to be received")
+	}
+
+	// resume while the paused receiving is waiting for the in-flight message
+	if err := subscriber.Pause(sub.ID()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := subscriber.Resume(sub.ID()); err != nil {
+		t.Fatal(err)
+	}
+	// wait for the paused receiving to stop after the in-flight message is processed
+	time.Sleep(1 * time.Second)
+
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("next")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message is expected to be received after resumed")
+	}
+	if got := subscriber.Status()[sub.ID()].State; got != ReceiverStateRunning {
+		t.Errorf("state = %v, want %v", got, ReceiverStateRunning)
+	}
+}
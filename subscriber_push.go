@@ -0,0 +1,91 @@
+package pm
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// pushRequest represents the request body sent from push subscription.
+// See https://cloud.google.com/pubsub/docs/push#receive_push
+type pushRequest struct {
+	Message         pushMessage `json:"message"`
+	Subscription    string      `json:"subscription"`
+	DeliveryAttempt *int        `json:"deliveryAttempt"`
+}
+
+type pushMessage struct {
+	Attributes  map[string]string `json:"attributes"`
+	Data        []byte            `json:"data"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+	OrderingKey string            `json:"orderingKey"`
+}
+
+// PushHandler returns http.Handler which handles messages delivered by push subscriptions.
+// The message is handled by the handler registered for the subscription in the request with applying
+// the same interceptors as pull subscriptions, which are chained once for each subscription on the first request.
+// Since ack / nack of the pushed message can't be observed, the response is decided by the returned error;
+// the message is acked with 2xx response when the handler returns nil, otherwise it's nacked with 5xx response.
+// The error marked by Permanent is acked with 2xx response, and the one marked by RetryAfter is responded with
+// 503 and Retry-After header.
+func (s *Subscriber) PushHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req pushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid push request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		subscriptionID := req.Subscription[strings.LastIndex(req.Subscription, "/")+1:]
+		s.mu.RLock()
+		h, ok := s.subscriptionHandlers[subscriptionID]
+		s.mu.RUnlock()
+		if !ok {
+			http.Error(w, "handler for subscription '"+subscriptionID+"' is not registered", http.StatusNotFound)
+			return
+		}
+
+		if req.Message.Attributes == nil {
+			req.Message.Attributes = map[string]string{}
+		}
+		m := &pubsub.Message{
+			ID:              req.Message.MessageID,
+			Data:            req.Message.Data,
+			Attributes:      req.Message.Attributes,
+			PublishTime:     req.Message.PublishTime,
+			DeliveryAttempt: req.DeliveryAttempt,
+			OrderingKey:     req.Message.OrderingKey,
+		}
+		h.pushOnce.Do(func() {
+			h.pushInfo, h.pushHandler = s.chainInterceptors(h)
+		})
+		if err := h.pushHandler(withMessageContext(r.Context(), h.pushInfo, m), m); err != nil {
+			writePushError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func writePushError(w http.ResponseWriter, err error) {
+	switch decision, delay := AckDecisionOf(err); decision {
+	case AckDecisionPermanent:
+		w.WriteHeader(http.StatusNoContent)
+	case AckDecisionRetryAfter:
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
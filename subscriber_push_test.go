@@ -0,0 +1,158 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestSubscriber_PushHandler(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_PushHandler_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_PushHandler_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotInfo *SubscriptionInfo
+	chains := 0
+	subscriber := NewSubscriber(pubsubClient, WithSubscriptionInterceptor(func(info *SubscriptionInfo, next MessageHandler) MessageHandler {
+		chains++
+		return func(ctx context.Context, m *pubsub.Message) error {
+			gotInfo = info
+			return next(ctx, m)
+		}
+	}))
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		if m.ID != "message-id" {
+			t.Errorf("message id = %v, want %v", m.ID, "message-id")
+		}
+		if m.DeliveryAttempt == nil || *m.DeliveryAttempt != 3 {
+			t.Errorf("delivery attempt = %v, want %v", m.DeliveryAttempt, 3)
+		}
+		if got, _ := DeliveryAttemptFromContext(ctx); got != 3 {
+			t.Errorf("DeliveryAttemptFromContext() = %v, want %v", got, 3)
+		}
+		if got, _ := SubscriptionInfoFromContext(ctx); got == nil || got.SubscriptionID != sub.ID() {
+			t.Errorf("SubscriptionInfoFromContext() = %v, want subscription id %v", got, sub.ID())
+		}
+		switch string(m.Data) {
+		case "error":
+			return errors.New("error")
+		case "permanent":
+			return Permanent(errors.New("error"))
+		case "retry":
+			return RetryAfter(errors.New("error"), 1500*time.Millisecond)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newBody := func(subscriptionID string, data string) string {
+		return fmt.Sprintf(`{
+			"message": {
+				"attributes": {"key": "value"},
+				"data": "%s",
+				"messageId": "message-id",
+				"publishTime": "2021-02-26T19:13:55.749Z"
+			},
+			"subscription": "projects/test/subscriptions/%s",
+			"deliveryAttempt": 3
+		}`, data, subscriptionID)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{
+			name:     "acks with 2xx when the handler returns nil",
+			method:   http.MethodPost,
+			body:     newBody(sub.ID(), "dGVzdA=="), // test
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "nacks with 5xx when the handler returns error",
+			method:   http.MethodPost,
+			body:     newBody(sub.ID(), "ZXJyb3I="), // error
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "acks with 2xx when the handler returns permanent error",
+			method:   http.MethodPost,
+			body:     newBody(sub.ID(), "cGVybWFuZW50"), // permanent
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:           "responds 503 with Retry-After when the handler returns retry after error",
+			method:         http.MethodPost,
+			body:           newBody(sub.ID(), "cmV0cnk="), // retry
+			wantCode:       http.StatusServiceUnavailable,
+			wantRetryAfter: "2",
+		},
+		{
+			name:     "responds 404 for not registered subscription",
+			method:   http.MethodPost,
+			body:     newBody("unknown", "dGVzdA=="),
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "responds 400 for invalid body",
+			method:   http.MethodPost,
+			body:     "invalid",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "responds 405 for non POST request",
+			method:   http.MethodGet,
+			body:     "",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			subscriber.PushHandler().ServeHTTP(rec, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Errorf("PushHandler() responds %v, want %v", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("PushHandler() responds Retry-After %v, want %v", got, tt.wantRetryAfter)
+			}
+		})
+	}
+
+	if gotInfo == nil || gotInfo.SubscriptionID != sub.ID() || gotInfo.TopicID != topic.ID() {
+		t.Errorf("interceptor is called with %+v, want subscription '%s' of topic '%s'", gotInfo, sub.ID(), topic.ID())
+	}
+	// the interceptors are chained once, so that their state is kept among the requests
+	if chains != 1 {
+		t.Errorf("interceptor is chained %v times, want 1", chains)
+	}
+}
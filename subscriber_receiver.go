@@ -0,0 +1,149 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// receiver receives messages of a subscription and tracks the in-flight messages.
+type receiver struct {
+	info          *SubscriptionInfo
+	subscription  *pubsub.Subscription
+	handleFunc    MessageHandler
+	restartPolicy *RestartPolicy
+	receiveCtx    context.Context
+	handlerCtx    context.Context
+	cancel        context.CancelFunc
+	cancelHandler context.CancelFunc
+	inFlight      *inFlightMessages
+	status        receiverStatus
+	stopped       chan struct{}
+
+	pauseMu       sync.Mutex
+	cancelReceive context.CancelFunc
+	// resumed is closed when the paused receiver is resumed, nil when it's not paused.
+	resumed chan struct{}
+	// pausedReceive is true when the current receiving is canceled by pause,
+	// so that the receiving is restarted even if it's resumed before the receiving stops.
+	pausedReceive bool
+}
+
+func newReceiver(
+	receiveCtx, handlerCtx context.Context,
+	info *SubscriptionInfo,
+	subscription *pubsub.Subscription,
+	handleFunc MessageHandler,
+	restartPolicy *RestartPolicy,
+) *receiver {
+	inFlight := newInFlightMessages()
+	receiveCtx, cancel := context.WithCancel(receiveCtx)
+	handlerCtx, cancelHandler := context.WithCancel(handlerCtx)
+	return &receiver{
+		info:          info,
+		subscription:  subscription,
+		handleFunc:    handleFunc,
+		restartPolicy: restartPolicy,
+		receiveCtx:    receiveCtx,
+		handlerCtx:    withDrainSignal(handlerCtx, inFlight.drainSignal),
+		cancel:        cancel,
+		cancelHandler: cancelHandler,
+		inFlight:      inFlight,
+		status:        receiverStatus{state: ReceiverStateRunning},
+		stopped:       make(chan struct{}),
+	}
+}
+
+// run blocks until receiving is stopped.
+// When restartPolicy is set, receiving is restarted on failure until it reaches the max restarts.
+// The restarts are reset once receiving runs healthily, that is, it receives a message or keeps running
+// for the max backoff, so that the transient failures spread over the lifetime don't exhaust the restarts.
+func (r *receiver) run() (err error) {
+	defer close(r.stopped)
+	defer func() {
+		if err != nil {
+			r.status.setState(ReceiverStateFailed, err)
+		} else {
+			r.status.setState(ReceiverStateStopped, nil)
+		}
+	}()
+	restarts := 0
+	for {
+		startedAt := time.Now()
+		received, err := r.receive()
+		if r.receiveCtx.Err() != nil {
+			return err
+		}
+		if r.takePaused() {
+			r.waitResumed()
+			continue
+		}
+		if err == nil || r.restartPolicy == nil {
+			return err
+		}
+		if received || time.Since(startedAt) >= r.restartPolicy.MaxBackoff {
+			restarts = 0
+		}
+		if !r.restartPolicy.canRestart(restarts) {
+			return fmt.Errorf("gave up restarting after %d restarts: %w", restarts, err)
+		}
+
+		restarts++
+		backoff := r.restartPolicy.backoff(restarts)
+		r.status.setState(ReceiverStateRestarting, err)
+		if r.restartPolicy.OnRestart != nil {
+			r.restartPolicy.OnRestart(r.info, restarts, err, backoff)
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+			r.status.setState(ReceiverStateRunning, nil)
+		case <-r.receiveCtx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// receive receives messages until it's stopped, and reports whether any message is received.
+func (r *receiver) receive() (bool, error) {
+	ctx, cancel := context.WithCancel(r.receiveCtx)
+	defer cancel()
+	if !r.setCancelReceive(cancel) {
+		// paused before starting receiving, e.g. while waiting for the restart backoff
+		r.status.setState(ReceiverStatePaused, nil)
+		return false, nil
+	}
+	defer r.setCancelReceive(nil)
+	var received atomic.Bool
+	err := r.subscription.Receive(ctx, func(_ context.Context, m *pubsub.Message) {
+		received.Store(true)
+		r.status.received()
+		r.inFlight.add(m)
+		defer r.inFlight.done(m)
+		_ = r.handleFunc(withMessageContext(r.handlerCtx, r.info, m), m)
+	})
+	return received.Load(), err
+}
+
+// stop stops receiving and waits for the in-flight messages to be processed until ctx is done.
+func (r *receiver) stop(ctx context.Context) (*ShutdownReport, error) {
+	r.inFlight.startDraining()
+	r.cancel()
+
+	var err error
+	select {
+	case <-r.stopped:
+	case <-ctx.Done():
+		r.inFlight.abandon()
+		err = ctx.Err()
+	}
+	r.cancelHandler()
+
+	drained, abandoned := r.inFlight.counts()
+	return &ShutdownReport{Drained: drained, Abandoned: abandoned}, err
+}
@@ -0,0 +1,87 @@
+package pm
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RestartPolicy defines how to restart the receiver of a subscription which stopped with error.
+type RestartPolicy struct {
+	// The maximum number of consecutive restarts for each subscription.
+	// The count is reset once the restarted receiver receives a message or keeps running for MaxBackoff.
+	// When a subscription still fails after reaching this limit, the error is returned from Wait.
+	// Negative value means unlimited. To disable restarting, don't set the policy by WithRestartPolicy.
+	// Defaults to DefaultRestartPolicy.MaxRestarts.
+	MaxRestarts int
+
+	// The backoff before the first restart.
+	// Defaults to DefaultRestartPolicy.InitialBackoff.
+	InitialBackoff time.Duration
+
+	// The upper limit of the backoff.
+	// Defaults to DefaultRestartPolicy.MaxBackoff.
+	MaxBackoff time.Duration
+
+	// The factor the backoff is multiplied by for each restart.
+	// Defaults to DefaultRestartPolicy.Multiplier.
+	Multiplier float64
+
+	// The ratio of the random jitter added to the backoff, between 0 and 1.
+	// e.g. when the backoff is 10s and Jitter is 0.2, the actual backoff is between 8s and 12s.
+	Jitter float64
+
+	// OnRestart is called before each restart attempt.
+	OnRestart RestartHook
+}
+
+// RestartHook is called before restarting the receiver of a subscription.
+// attempt starts from 1, err is the error the receiver stopped with.
+type RestartHook func(info *SubscriptionInfo, attempt int, err error, backoff time.Duration)
+
+var DefaultRestartPolicy = &RestartPolicy{
+	MaxRestarts:    10,
+	InitialBackoff: 1 * time.Second,
+	MaxBackoff:     1 * time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (p *RestartPolicy) withDefaults() *RestartPolicy {
+	policy := *p
+	if policy.MaxRestarts == 0 {
+		policy.MaxRestarts = DefaultRestartPolicy.MaxRestarts
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = DefaultRestartPolicy.InitialBackoff
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = DefaultRestartPolicy.MaxBackoff
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = DefaultRestartPolicy.Multiplier
+	}
+	return &policy
+}
+
+// canRestart reports whether the receiver can be restarted after the given number of restarts.
+func (p *RestartPolicy) canRestart(restarts int) bool {
+	return p.MaxRestarts < 0 || restarts < p.MaxRestarts
+}
+
+// backoff returns the backoff before the given attempt's restart.
+func (p *RestartPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if backoff > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
@@ -0,0 +1,235 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestRestartPolicy_withDefaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		policy RestartPolicy
+		want   RestartPolicy
+	}{
+		{
+			name:   "zero values are replaced with the default values",
+			policy: RestartPolicy{},
+			want:   *DefaultRestartPolicy,
+		},
+		{
+			name:   "set values are kept",
+			policy: RestartPolicy{MaxRestarts: -1, InitialBackoff: time.Second, MaxBackoff: time.Second, Multiplier: 3},
+			want:   RestartPolicy{MaxRestarts: -1, InitialBackoff: time.Second, MaxBackoff: time.Second, Multiplier: 3},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tt.policy.withDefaults()
+			// Jitter is not defaulted since 0 means no jitter
+			tt.want.Jitter = tt.policy.Jitter
+			if got.MaxRestarts != tt.want.MaxRestarts || got.InitialBackoff != tt.want.InitialBackoff ||
+				got.MaxBackoff != tt.want.MaxBackoff || got.Multiplier != tt.want.Multiplier || got.Jitter != tt.want.Jitter {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+			if !got.canRestart(0) {
+				t.Error("canRestart(0) = false, want true")
+			}
+		})
+	}
+}
+
+func TestRestartPolicy_backoff(t *testing.T) {
+	t.Parallel()
+
+	policy := (&RestartPolicy{
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}).withDefaults()
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 1 * time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 5 * time.Second},
+		{attempt: 100, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			t.Parallel()
+			if got := policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("jitter is added within the ratio", func(t *testing.T) {
+		t.Parallel()
+
+		policy := (&RestartPolicy{InitialBackoff: 10 * time.Second, Jitter: 0.2}).withDefaults()
+		for i := 0; i < 100; i++ {
+			if got := policy.backoff(1); got < 8*time.Second || got > 12*time.Second {
+				t.Fatalf("backoff() = %v, want between %v and %v", got, 8*time.Second, 12*time.Second)
+			}
+		}
+	})
+}
+
+func TestSubscriber_Run_withRestartPolicy(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Run_withRestartPolicy_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_Run_withRestartPolicy_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var attempts []int
+	subscriber := NewSubscriber(pubsubClient, WithRestartPolicy(RestartPolicy{
+		MaxRestarts:    2,
+		InitialBackoff: 10 * time.Millisecond,
+		OnRestart: func(info *SubscriptionInfo, attempt int, err error, backoff time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			if info.SubscriptionID != sub.ID() {
+				t.Errorf("OnRestart() is called with subscription id %v, want %v", info.SubscriptionID, sub.ID())
+			}
+			attempts = append(attempts, attempt)
+		},
+	}))
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// receiving the deleted subscription keeps failing
+	if err := sub.Delete(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.RunAndWait(context.Background())
+	}()
+
+	select {
+	case err := <-errCh:
+		var runErr RunError
+		if !errors.As(err, &runErr) {
+			t.Fatalf("RunAndWait() error = %v, want RunError", err)
+		}
+		if _, ok := runErr[sub.ID()]; !ok {
+			t.Errorf("RunAndWait() error = %v, want error for subscription '%s'", err, sub.ID())
+		}
+	case <-time.After(10 * time.Second):
+		subscriber.Close()
+		t.Fatal("RunAndWait() is expected to return after reaching the max restarts")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int{1, 2}; fmt.Sprint(attempts) != fmt.Sprint(want) {
+		t.Errorf("OnRestart() is called with attempts %v, want %v", attempts, want)
+	}
+}
+
+func TestSubscriber_Run_withRestartPolicy_reset(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Run_withRestartPolicy_reset_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_Run_withRestartPolicy_reset_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var attempts []int
+	// receiving keeps running longer than the max backoff before failing, so the restarts are reset every time
+	subscriber := NewSubscriber(pubsubClient, WithRestartPolicy(RestartPolicy{
+		MaxRestarts:    1,
+		InitialBackoff: time.Microsecond,
+		MaxBackoff:     time.Microsecond,
+		OnRestart: func(info *SubscriptionInfo, attempt int, err error, backoff time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts = append(attempts, attempt)
+		},
+	}))
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Delete(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.RunAndWait(context.Background())
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("RunAndWait() is expected to keep restarting, got error: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	subscriber.Close()
+	<-errCh
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) < 2 {
+		t.Fatalf("OnRestart() is called %d times, want more than the max restarts", len(attempts))
+	}
+	for _, attempt := range attempts {
+		if attempt != 1 {
+			t.Errorf("OnRestart() is called with attempts %v, want all 1", attempts)
+			break
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package pm
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+	"golang.org/x/sync/errgroup"
+)
+
+// ShutdownReport represents the result of Subscriber.Shutdown.
+type ShutdownReport struct {
+	// Drained is the number of in-flight messages which finished processing during shutdown.
+	Drained int
+	// Abandoned is the number of in-flight messages which were nacked
+	// since they didn't finish processing before the deadline.
+	Abandoned int
+}
+
+// Shutdown stops pulling messages and waits for in-flight messages to be processed.
+// Pending messages of the batch message handlers are flushed immediately.
+// When ctx is done before all in-flight messages are processed, the remaining messages are nacked
+// and the context passed to their handlers is canceled, then ctx's error is returned.
+func (s *Subscriber) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	s.mu.RLock()
+	cancel, cancelHandlers := s.cancel, s.cancelHandlers
+	receivers := make([]*receiver, 0, len(s.receivers))
+	for _, r := range s.receivers {
+		receivers = append(receivers, r)
+	}
+	s.mu.RUnlock()
+	if cancel == nil {
+		return &ShutdownReport{}, nil
+	}
+
+	for _, r := range receivers {
+		r.inFlight.startDraining()
+	}
+	cancel()
+
+	var mu sync.Mutex
+	report := &ShutdownReport{}
+	eg := errgroup.Group{}
+	for _, r := range receivers {
+		r := r
+		eg.Go(func() error {
+			rr, err := r.stop(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Drained += rr.Drained
+			report.Abandoned += rr.Abandoned
+			return err
+		})
+	}
+	err := eg.Wait()
+	cancelHandlers()
+
+	return report, err
+}
+
+type inFlightMessages struct {
+	mu          sync.Mutex
+	messages    map[*pubsub.Message]struct{}
+	draining    bool
+	drainSignal chan struct{}
+	drained     int
+	abandoned   int
+}
+
+func newInFlightMessages() *inFlightMessages {
+	return &inFlightMessages{
+		messages:    map[*pubsub.Message]struct{}{},
+		drainSignal: make(chan struct{}),
+	}
+}
+
+func (i *inFlightMessages) add(m *pubsub.Message) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.messages[m] = struct{}{}
+}
+
+func (i *inFlightMessages) done(m *pubsub.Message) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	// the message is already abandoned
+	if _, ok := i.messages[m]; !ok {
+		return
+	}
+	delete(i.messages, m)
+	if i.draining {
+		i.drained++
+	}
+}
+
+func (i *inFlightMessages) startDraining() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.draining {
+		return
+	}
+	i.draining = true
+	close(i.drainSignal)
+}
+
+func (i *inFlightMessages) abandon() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for m := range i.messages {
+		m.Nack()
+		i.abandoned++
+	}
+	i.messages = map[*pubsub.Message]struct{}{}
+}
+
+func (i *inFlightMessages) counts() (drained int, abandoned int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.drained, i.abandoned
+}
+
+type drainSignalKey struct{}
+
+func withDrainSignal(ctx context.Context, drainSignal <-chan struct{}) context.Context {
+	return context.WithValue(ctx, drainSignalKey{}, drainSignal)
+}
+
+// DrainSignalFromContext returns the channel which is closed when the subscriber starts draining
+// the in-flight messages, e.g. by Shutdown or Unsubscribe, so that the handler holding the message
+// can stop waiting. When the context is not passed by the subscriber, nil channel is returned.
+func DrainSignalFromContext(ctx context.Context) <-chan struct{} {
+	drainSignal, _ := ctx.Value(drainSignalKey{}).(<-chan struct{})
+	return drainSignal
+}
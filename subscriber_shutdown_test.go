@@ -0,0 +1,130 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestSubscriber_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Shutdown_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// startSubscriber runs a subscriber with the given handler and returns it after the handler started processing a message.
+	startSubscriber := func(t *testing.T, id string, handler MessageHandler) *Subscriber {
+		t.Helper()
+
+		sub, err := pubsubClient.CreateSubscription(
+			context.Background(),
+			fmt.Sprintf("%s_%d", id, time.Now().Unix()),
+			pubsub.SubscriptionConfig{Topic: topic, Filter: fmt.Sprintf("attributes.test = \"%s\"", id)},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		started := make(chan struct{}, 1)
+		subscriber := NewSubscriber(pubsubClient, WithSubscriptionInterceptor(func(_ *SubscriptionInfo, next MessageHandler) MessageHandler {
+			return func(ctx context.Context, m *pubsub.Message) error {
+				started <- struct{}{}
+				return next(ctx, m)
+			}
+		}))
+		if err := subscriber.HandleSubscriptionFunc(sub, handler); err != nil {
+			t.Fatal(err)
+		}
+		subscriber.Run(context.Background())
+
+		ctx := context.Background()
+		if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test"), Attributes: map[string]string{"test": id}}).Get(ctx); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-started:
+		case <-time.After(10 * time.Second):
+			t.Fatal("message is not received")
+		}
+		return subscriber
+	}
+
+	t.Run("waits for in-flight messages to be processed", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := startSubscriber(t, "TestSubscriber_Shutdown_Drain", func(ctx context.Context, m *pubsub.Message) error {
+			time.Sleep(100 * time.Millisecond)
+			if err := ctx.Err(); err != nil {
+				t.Errorf("context passed to the handler is canceled while draining: %v", err)
+			}
+			m.Ack()
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		report, err := subscriber.Shutdown(ctx)
+		if err != nil {
+			t.Fatalf("Shutdown() error = %v, want nil", err)
+		}
+		if want := (ShutdownReport{Drained: 1}); *report != want {
+			t.Errorf("Shutdown() = %+v, want %+v", *report, want)
+		}
+	})
+
+	t.Run("flushes pending messages of batch message handler", func(t *testing.T) {
+		t.Parallel()
+
+		var processed int
+		subscriber := startSubscriber(t, "TestSubscriber_Shutdown_Batch", NewBatchMessageHandler(func(messages []*pubsub.Message) error {
+			processed += len(messages)
+			for _, m := range messages {
+				m.Ack()
+			}
+			return nil
+		}, BatchMessageHandlerConfig{DelayThreshold: time.Hour}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		report, err := subscriber.Shutdown(ctx)
+		if err != nil {
+			t.Fatalf("Shutdown() error = %v, want nil", err)
+		}
+		if want := (ShutdownReport{Drained: 1}); *report != want {
+			t.Errorf("Shutdown() = %+v, want %+v", *report, want)
+		}
+		if processed != 1 {
+			t.Errorf("processed messages = %v, want %v", processed, 1)
+		}
+	})
+
+	t.Run("abandons in-flight messages when the deadline exceeded", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := startSubscriber(t, "TestSubscriber_Shutdown_Abandon", func(ctx context.Context, m *pubsub.Message) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		report, err := subscriber.Shutdown(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if want := (ShutdownReport{Abandoned: 1}); *report != want {
+			t.Errorf("Shutdown() = %+v, want %+v", *report, want)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package pm
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReceiverState represents the state of the receiver of a subscription.
+type ReceiverState string
+
+const (
+	// ReceiverStateIdle means the subscription is registered but the subscriber is not running.
+	ReceiverStateIdle ReceiverState = "idle"
+	// ReceiverStateRunning means the subscription is receiving messages.
+	ReceiverStateRunning ReceiverState = "running"
+	// ReceiverStatePaused means the receiver is paused by Subscriber.Pause.
+	ReceiverStatePaused ReceiverState = "paused"
+	// ReceiverStateRestarting means the receiver failed and is waiting for the backoff to restart.
+	ReceiverStateRestarting ReceiverState = "restarting"
+	// ReceiverStateStopped means the receiver is stopped without error.
+	ReceiverStateStopped ReceiverState = "stopped"
+	// ReceiverStateFailed means the receiver is stopped with error.
+	ReceiverStateFailed ReceiverState = "failed"
+)
+
+// SubscriptionStatus represents the status of a subscription's receiver.
+type SubscriptionStatus struct {
+	TopicID        string        `json:"topicId"`
+	SubscriptionID string        `json:"subscriptionId"`
+	State          ReceiverState `json:"state"`
+	// Restarts is the number of restarts by RestartPolicy.
+	Restarts int `json:"restarts"`
+	// LastError is the last error the receiver stopped with.
+	LastError string `json:"lastError,omitempty"`
+	// LastReceivedAt is the time the last message was received, zero if no message received yet.
+	LastReceivedAt time.Time `json:"lastReceivedAt"`
+}
+
+// Status returns the status of all the registered subscriptions.
+// The key is subscription id
+func (s *Subscriber) Status() map[string]SubscriptionStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make(map[string]SubscriptionStatus, len(s.subscriptionHandlers))
+	for subscriptionID, h := range s.subscriptionHandlers {
+		if r, ok := s.receivers[subscriptionID]; ok {
+			statuses[subscriptionID] = r.status.snapshot(r.info)
+			continue
+		}
+		statuses[subscriptionID] = SubscriptionStatus{
+			TopicID:        h.info.TopicID,
+			SubscriptionID: subscriptionID,
+			State:          ReceiverStateIdle,
+		}
+	}
+	return statuses
+}
+
+// LivenessHandler returns http.Handler for liveness probe.
+// It responds with 503 when any of the receivers is failed, otherwise 200.
+// The response body is the status of all the subscriptions in JSON.
+func (s *Subscriber) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := s.Status()
+		healthy := true
+		for _, status := range statuses {
+			if status.State == ReceiverStateFailed {
+				healthy = false
+			}
+		}
+		writeStatuses(w, healthy, statuses)
+	})
+}
+
+// ReadinessHandler returns http.Handler for readiness probe.
+// It responds with 200 only when all the registered subscriptions are running, otherwise 503.
+// The response body is the status of all the subscriptions in JSON.
+func (s *Subscriber) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := s.Status()
+		ready := true
+		for _, status := range statuses {
+			if status.State != ReceiverStateRunning {
+				ready = false
+			}
+		}
+		writeStatuses(w, ready, statuses)
+	})
+}
+
+func writeStatuses(w http.ResponseWriter, ok bool, statuses map[string]SubscriptionStatus) {
+	list := make([]SubscriptionStatus, 0, len(statuses))
+	for _, status := range statuses {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SubscriptionID < list[j].SubscriptionID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(struct {
+		Subscriptions []SubscriptionStatus `json:"subscriptions"`
+	}{Subscriptions: list})
+}
+
+type receiverStatus struct {
+	mu             sync.RWMutex
+	state          ReceiverState
+	restarts       int
+	lastErr        error
+	lastReceivedAt time.Time
+}
+
+func (r *receiverStatus) setState(state ReceiverState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = state
+	if err != nil {
+		r.lastErr = err
+	}
+	if state == ReceiverStateRestarting {
+		r.restarts++
+	}
+}
+
+func (r *receiverStatus) received() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastReceivedAt = time.Now()
+}
+
+func (r *receiverStatus) snapshot(info *SubscriptionInfo) SubscriptionStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status := SubscriptionStatus{
+		TopicID:        info.TopicID,
+		SubscriptionID: info.SubscriptionID,
+		State:          r.state,
+		Restarts:       r.restarts,
+		LastReceivedAt: r.lastReceivedAt,
+	}
+	if r.lastErr != nil {
+		status.LastError = r.lastErr.Error()
+	}
+	return status
+}
@@ -0,0 +1,163 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestSubscriber_Status(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Status_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_Status_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := NewSubscriber(pubsubClient)
+	defer subscriber.Close()
+	received := make(chan struct{}, 1)
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		received <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := subscriber.Status()[sub.ID()].State; got != ReceiverStateIdle {
+		t.Errorf("Status() state before Run = %v, want %v", got, ReceiverStateIdle)
+	}
+
+	subscriber.Run(context.Background())
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("message is not received")
+	}
+
+	status := subscriber.Status()[sub.ID()]
+	if status.State != ReceiverStateRunning {
+		t.Errorf("Status() state after Run = %v, want %v", status.State, ReceiverStateRunning)
+	}
+	if status.TopicID != topic.ID() {
+		t.Errorf("Status() topic id = %v, want %v", status.TopicID, topic.ID())
+	}
+	if status.LastReceivedAt.IsZero() {
+		t.Error("Status() last received at is expected to be set after receiving message")
+	}
+
+	if _, err := subscriber.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := subscriber.Status()[sub.ID()].State; got != ReceiverStateStopped {
+		t.Errorf("Status() state after Shutdown = %v, want %v", got, ReceiverStateStopped)
+	}
+}
+
+func TestSubscriber_HealthHandlers(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_HealthHandlers_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createSubscription := func(t *testing.T, id string) *pubsub.Subscription {
+		t.Helper()
+		sub, err := pubsubClient.CreateSubscription(
+			context.Background(),
+			fmt.Sprintf("%s_%d", id, time.Now().Unix()),
+			pubsub.SubscriptionConfig{Topic: topic},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sub
+	}
+	handler := func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		return nil
+	}
+	serve := func(h http.Handler) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	t.Run("responds ok when all receivers are running", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber(pubsubClient)
+		defer subscriber.Close()
+		if err := subscriber.HandleSubscriptionFunc(createSubscription(t, "TestSubscriber_HealthHandlers_Running"), handler); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := serve(subscriber.ReadinessHandler()); got != http.StatusServiceUnavailable {
+			t.Errorf("ReadinessHandler() before Run responds %v, want %v", got, http.StatusServiceUnavailable)
+		}
+
+		subscriber.Run(context.Background())
+		if got := serve(subscriber.LivenessHandler()); got != http.StatusOK {
+			t.Errorf("LivenessHandler() responds %v, want %v", got, http.StatusOK)
+		}
+		if got := serve(subscriber.ReadinessHandler()); got != http.StatusOK {
+			t.Errorf("ReadinessHandler() responds %v, want %v", got, http.StatusOK)
+		}
+	})
+
+	t.Run("responds service unavailable when a receiver is failed", func(t *testing.T) {
+		t.Parallel()
+
+		sub := createSubscription(t, "TestSubscriber_HealthHandlers_Failed")
+		subscriber := NewSubscriber(pubsubClient)
+		defer subscriber.Close()
+		if err := subscriber.HandleSubscriptionFunc(sub, handler); err != nil {
+			t.Fatal(err)
+		}
+		if err := sub.Delete(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		_ = subscriber.RunAndWait(context.Background())
+		if got := subscriber.Status()[sub.ID()].State; got != ReceiverStateFailed {
+			t.Errorf("Status() state = %v, want %v", got, ReceiverStateFailed)
+		}
+		if got := serve(subscriber.LivenessHandler()); got != http.StatusServiceUnavailable {
+			t.Errorf("LivenessHandler() responds %v, want %v", got, http.StatusServiceUnavailable)
+		}
+		if got := serve(subscriber.ReadinessHandler()); got != http.StatusServiceUnavailable {
+			t.Errorf("ReadinessHandler() responds %v, want %v", got, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package pm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
@@ -137,6 +138,57 @@ func TestSubscriber_HandleSubscriptionFunc(t *testing.T) {
 	}
 }
 
+func TestSubscriber_HandleSubscriptionFunc_withSubscriptionOption(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_HandleSubscriptionFunc_withSubscriptionOption_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_HandleSubscriptionFunc_withSubscriptionOption_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.ReceiveSettings.MinExtensionPeriod = 30 * time.Second
+
+	subscriber := NewSubscriber(pubsubClient)
+	err = subscriber.HandleSubscriptionFunc(
+		sub,
+		func(ctx context.Context, m *pubsub.Message) error { return nil },
+		WithMaxOutstandingMessages(10),
+		WithMaxOutstandingBytes(1e6),
+		WithNumGoroutines(2),
+		WithMaxExtension(time.Minute),
+		WithSynchronous(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := pubsub.ReceiveSettings{
+		MaxExtension:           time.Minute,
+		MinExtensionPeriod:     30 * time.Second,
+		MaxOutstandingMessages: 10,
+		MaxOutstandingBytes:    1e6,
+		NumGoroutines:          2,
+		Synchronous:            true,
+	}
+	got := subscriber.subscriptionHandlers[sub.ID()].subscription.ReceiveSettings
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HandleSubscriptionFunc() set ReceiveSettings = %+v, want %+v", got, want)
+	}
+}
+
 func TestSubscriber_HandleSubscriptionFuncMap(t *testing.T) {
 	t.Parallel()
 
@@ -277,6 +329,170 @@ func TestSubscriber_Run(t *testing.T) {
 	}
 }
 
+func TestSubscriber_Run_withInterceptor(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Run_withInterceptor_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_Run_withInterceptor_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appendAttribute := func(value string) SubscriptionInterceptor {
+		return func(_ *SubscriptionInfo, next MessageHandler) MessageHandler {
+			return func(ctx context.Context, m *pubsub.Message) error {
+				m.Attributes["intercepted"] += value
+				return next(ctx, m)
+			}
+		}
+	}
+	subscriber := NewSubscriber(pubsubClient, WithSubscriptionInterceptor(appendAttribute("global,")))
+	defer subscriber.Close()
+
+	received := make(chan string, 1)
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		received <- m.Attributes["intercepted"]
+		return nil
+	}, WithInterceptor(appendAttribute("local1,"), appendAttribute("local2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber.Run(context.Background())
+
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test"), Attributes: map[string]string{"intercepted": ""}}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-received:
+		if want := "global,local1,local2"; got != want {
+			t.Errorf("interceptors are applied in order %v, want %v", got, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("message is not received")
+	}
+}
+
+func TestSubscriber_Run_registerWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Run_registerWhileRunning_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_Run_registerWhileRunning_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := NewSubscriber(pubsubClient)
+	defer subscriber.Close()
+	subscriber.Run(context.Background())
+
+	received := make(chan struct{}, 1)
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		received <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Error("subscription registered while running is expected to be received")
+	}
+}
+
+func TestSubscriber_Unsubscribe(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_Unsubscribe_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := pubsubClient.CreateSubscription(
+		context.Background(),
+		fmt.Sprintf("TestSubscriber_Unsubscribe_%d", time.Now().Unix()),
+		pubsub.SubscriptionConfig{Topic: topic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := NewSubscriber(pubsubClient)
+	defer subscriber.Close()
+
+	err = subscriber.HandleSubscriptionFunc(sub, func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		t.Error("Must not received messages after unsubscribe")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriber.Run(context.Background())
+
+	report, err := subscriber.Unsubscribe(context.Background(), sub.ID())
+	if err != nil {
+		t.Fatalf("Unsubscribe() error = %v, want nil", err)
+	}
+	if want := (ShutdownReport{}); *report != want {
+		t.Errorf("Unsubscribe() = %+v, want %+v", *report, want)
+	}
+	if _, ok := subscriber.subscriptionHandlers[sub.ID()]; ok {
+		t.Error("Unsubscribe() is expected to unregister subscription handler")
+	}
+	if _, err := subscriber.Unsubscribe(context.Background(), sub.ID()); err == nil {
+		t.Error("Unsubscribe() is expected to return error for not registered subscription")
+	}
+
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("test")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1 * time.Second)
+}
+
 func TestSubscriber_Close(t *testing.T) {
 	t.Parallel()
 
@@ -319,3 +535,163 @@ func TestSubscriber_Close(t *testing.T) {
 	}
 	time.Sleep(1 * time.Second)
 }
+
+func TestRunError_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		r    RunError
+		want string
+	}{
+		{
+			name: "returns error string sorted by subscription id",
+			r: RunError{
+				"sub-b": errors.New("error b"),
+				"sub-a": errors.New("error a"),
+			},
+			want: "error a for subscription 'sub-a', error b for subscription 'sub-b'",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.r.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriber_RunAndWait(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("TestSubscriber_RunAndWait_%d", time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createSubscription := func(t *testing.T, id string) *pubsub.Subscription {
+		t.Helper()
+		sub, err := pubsubClient.CreateSubscription(
+			context.Background(),
+			fmt.Sprintf("%s_%d", id, time.Now().Unix()),
+			pubsub.SubscriptionConfig{Topic: topic},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sub
+	}
+	handler := func(ctx context.Context, m *pubsub.Message) error {
+		m.Ack()
+		return nil
+	}
+
+	t.Run("returns nil when all subscriptions stop without error", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber(pubsubClient)
+		if err := subscriber.HandleSubscriptionFunc(createSubscription(t, "TestSubscriber_RunAndWait_Success"), handler); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := subscriber.RunAndWait(ctx); err != nil {
+			t.Errorf("RunAndWait() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("blocks until the context is done even without subscriptions", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber(pubsubClient)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := subscriber.RunAndWait(ctx); err != nil {
+			t.Errorf("RunAndWait() error = %v, want nil", err)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("RunAndWait() returned after %v, want to block until the context is done", elapsed)
+		}
+	})
+
+	t.Run("returns RunError including the failed subscription id", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber(pubsubClient, WithStopAllOnError())
+		healthySub := createSubscription(t, "TestSubscriber_RunAndWait_Healthy")
+		failingSub := createSubscription(t, "TestSubscriber_RunAndWait_Failing")
+		if err := subscriber.HandleSubscriptionFunc(healthySub, handler); err != nil {
+			t.Fatal(err)
+		}
+		if err := subscriber.HandleSubscriptionFunc(failingSub, handler); err != nil {
+			t.Fatal(err)
+		}
+		if err := failingSub.Delete(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- subscriber.RunAndWait(context.Background())
+		}()
+
+		select {
+		case err := <-errCh:
+			var runErr RunError
+			if !errors.As(err, &runErr) {
+				t.Fatalf("RunAndWait() error = %v, want RunError", err)
+			}
+			if _, ok := runErr[failingSub.ID()]; !ok {
+				t.Errorf("RunAndWait() error = %v, want error for subscription '%s'", err, failingSub.ID())
+			}
+			if _, ok := runErr[healthySub.ID()]; ok {
+				t.Errorf("RunAndWait() error = %v, want no error for subscription '%s'", err, healthySub.ID())
+			}
+		case <-time.After(10 * time.Second):
+			subscriber.Close()
+			t.Error("RunAndWait() is expected to return when a subscription fails with WithStopAllOnError")
+		}
+	})
+
+	t.Run("returns RunError when the only subscription fails without WithStopAllOnError", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber(pubsubClient)
+		failingSub := createSubscription(t, "TestSubscriber_RunAndWait_FailingOnly")
+		if err := subscriber.HandleSubscriptionFunc(failingSub, handler); err != nil {
+			t.Fatal(err)
+		}
+		if err := failingSub.Delete(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- subscriber.RunAndWait(context.Background())
+		}()
+
+		select {
+		case err := <-errCh:
+			var runErr RunError
+			if !errors.As(err, &runErr) {
+				t.Fatalf("RunAndWait() error = %v, want RunError", err)
+			}
+			if _, ok := runErr[failingSub.ID()]; !ok {
+				t.Errorf("RunAndWait() error = %v, want error for subscription '%s'", err, failingSub.ID())
+			}
+		case <-time.After(10 * time.Second):
+			subscriber.Close()
+			t.Error("RunAndWait() is expected to return when all the subscriptions stopped and one of them failed")
+		}
+	})
+}
@@ -1,10 +1,92 @@
 package pm
 
+import (
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
 // SubscriptionInfo contains various info about the subscription.
 type SubscriptionInfo struct {
 	TopicID        string
 	SubscriptionID string
+
+	// ProjectID is the project id of the subscription.
+	ProjectID string
+	// TopicName is the fully qualified topic name in the format "projects/<projid>/topics/<name>".
+	TopicName string
+	// SubscriptionName is the fully qualified subscription name in the format "projects/<projid>/subscriptions/<name>".
+	SubscriptionName string
+
+	AckDeadline               time.Duration
+	Filter                    string
+	EnableMessageOrdering     bool
+	EnableExactlyOnceDelivery bool
+	// DeadLetterPolicy is nil when dead lettering is not configured.
+	DeadLetterPolicy *pubsub.DeadLetterPolicy
+	// RetryPolicy is nil when retry policy is not configured.
+	RetryPolicy *pubsub.RetryPolicy
+	Labels      map[string]string
+}
+
+func newSubscriptionInfo(subscription *pubsub.Subscription, cfg pubsub.SubscriptionConfig) *SubscriptionInfo {
+	info := &SubscriptionInfo{
+		SubscriptionID:            subscription.ID(),
+		SubscriptionName:          subscription.String(),
+		AckDeadline:               cfg.AckDeadline,
+		Filter:                    cfg.Filter,
+		EnableMessageOrdering:     cfg.EnableMessageOrdering,
+		EnableExactlyOnceDelivery: cfg.EnableExactlyOnceDelivery,
+		DeadLetterPolicy:          cfg.DeadLetterPolicy,
+		RetryPolicy:               cfg.RetryPolicy,
+		Labels:                    cfg.Labels,
+	}
+	if cfg.Topic != nil {
+		info.TopicID = cfg.Topic.ID()
+		info.TopicName = cfg.Topic.String()
+	}
+	// subscription name is in the format "projects/<projid>/subscriptions/<name>"
+	if parts := strings.Split(info.SubscriptionName, "/"); len(parts) == 4 {
+		info.ProjectID = parts[1]
+	}
+	return info
 }
 
 // SubscriptionInterceptor provides a hook to intercept the execution of a message handling.
 type SubscriptionInterceptor = func(info *SubscriptionInfo, next MessageHandler) MessageHandler
+
+// ForSubscriptions applies the given interceptor only to the subscriptions matched by match.
+// For the other subscriptions, the interceptor is skipped.
+func ForSubscriptions(match func(info *SubscriptionInfo) bool, interceptor SubscriptionInterceptor) SubscriptionInterceptor {
+	return func(info *SubscriptionInfo, next MessageHandler) MessageHandler {
+		if !match(info) {
+			return next
+		}
+		return interceptor(info, next)
+	}
+}
+
+// MatchSubscriptionIDs returns a matcher for ForSubscriptions which matches any of the given subscription ids.
+func MatchSubscriptionIDs(subscriptionIDs ...string) func(info *SubscriptionInfo) bool {
+	return func(info *SubscriptionInfo) bool {
+		for _, subscriptionID := range subscriptionIDs {
+			if info.SubscriptionID == subscriptionID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchTopicIDs returns a matcher for ForSubscriptions which matches any of the given topic ids.
+func MatchTopicIDs(topicIDs ...string) func(info *SubscriptionInfo) bool {
+	return func(info *SubscriptionInfo) bool {
+		for _, topicID := range topicIDs {
+			if info.TopicID == topicID {
+				return true
+			}
+		}
+		return false
+	}
+}
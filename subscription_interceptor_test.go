@@ -0,0 +1,108 @@
+package pm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestForSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	interceptor := func(_ *SubscriptionInfo, next MessageHandler) MessageHandler {
+		return func(ctx context.Context, m *pubsub.Message) error {
+			m.Attributes["intercepted"] = "true"
+			return next(ctx, m)
+		}
+	}
+	next := func(ctx context.Context, m *pubsub.Message) error {
+		return nil
+	}
+
+	tests := []struct {
+		name            string
+		match           func(info *SubscriptionInfo) bool
+		info            *SubscriptionInfo
+		wantIntercepted bool
+	}{
+		{
+			name:            "applies interceptor to the matched subscription id",
+			match:           MatchSubscriptionIDs("sub-a", "sub-b"),
+			info:            &SubscriptionInfo{TopicID: "topic", SubscriptionID: "sub-b"},
+			wantIntercepted: true,
+		},
+		{
+			name:            "skips interceptor for the unmatched subscription id",
+			match:           MatchSubscriptionIDs("sub-a", "sub-b"),
+			info:            &SubscriptionInfo{TopicID: "topic", SubscriptionID: "sub-c"},
+			wantIntercepted: false,
+		},
+		{
+			name:            "applies interceptor to the matched topic id",
+			match:           MatchTopicIDs("topic-a"),
+			info:            &SubscriptionInfo{TopicID: "topic-a", SubscriptionID: "sub"},
+			wantIntercepted: true,
+		},
+		{
+			name:            "skips interceptor for the unmatched topic id",
+			match:           MatchTopicIDs("topic-a"),
+			info:            &SubscriptionInfo{TopicID: "topic-b", SubscriptionID: "sub"},
+			wantIntercepted: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &pubsub.Message{Attributes: map[string]string{}}
+			_ = ForSubscriptions(tt.match, interceptor)(tt.info, next)(context.Background(), m)
+			if got := m.Attributes["intercepted"] == "true"; got != tt.wantIntercepted {
+				t.Errorf("ForSubscriptions() intercepted = %v, want %v", got, tt.wantIntercepted)
+			}
+		})
+	}
+}
+
+func Test_newSubscriptionInfo(t *testing.T) {
+	t.Parallel()
+
+	pubsubClient, err := pubsub.NewClient(context.Background(), "test-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadLetterPolicy := &pubsub.DeadLetterPolicy{DeadLetterTopic: "projects/test-project/topics/dlq", MaxDeliveryAttempts: 5}
+	retryPolicy := &pubsub.RetryPolicy{MinimumBackoff: 10 * time.Second}
+	got := newSubscriptionInfo(pubsubClient.Subscription("test-sub"), pubsub.SubscriptionConfig{
+		Topic:                     pubsubClient.Topic("test-topic"),
+		AckDeadline:               30 * time.Second,
+		Filter:                    `attributes.key = "value"`,
+		EnableMessageOrdering:     true,
+		EnableExactlyOnceDelivery: true,
+		DeadLetterPolicy:          deadLetterPolicy,
+		RetryPolicy:               retryPolicy,
+		Labels:                    map[string]string{"team": "billing"},
+	})
+
+	want := &SubscriptionInfo{
+		TopicID:                   "test-topic",
+		SubscriptionID:            "test-sub",
+		ProjectID:                 "test-project",
+		TopicName:                 "projects/test-project/topics/test-topic",
+		SubscriptionName:          "projects/test-project/subscriptions/test-sub",
+		AckDeadline:               30 * time.Second,
+		Filter:                    `attributes.key = "value"`,
+		EnableMessageOrdering:     true,
+		EnableExactlyOnceDelivery: true,
+		DeadLetterPolicy:          deadLetterPolicy,
+		RetryPolicy:               retryPolicy,
+		Labels:                    map[string]string{"team": "billing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newSubscriptionInfo() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package pm
+
+import (
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+type subscriptionOptions struct {
+	receiveSettings          pubsub.ReceiveSettings
+	subscriptionInterceptors []SubscriptionInterceptor
+}
+
+// SubscriptionOption is a option to change configuration of each subscription.
+type SubscriptionOption interface {
+	apply(*subscriptionOptions)
+}
+
+type subscriptionOptionFunc struct {
+	f func(*subscriptionOptions)
+}
+
+func (s *subscriptionOptionFunc) apply(so *subscriptionOptions) {
+	s.f(so)
+}
+
+func newSubscriptionOptionFunc(f func(*subscriptionOptions)) *subscriptionOptionFunc {
+	return &subscriptionOptionFunc{
+		f: f,
+	}
+}
+
+// WithInterceptor sets subscription interceptors applied only to the subscription.
+// They run inside the interceptors set by WithSubscriptionInterceptor.
+func WithInterceptor(interceptors ...SubscriptionInterceptor) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.subscriptionInterceptors = interceptors
+	})
+}
+
+// WithReceiveSettings overwrites the whole receive settings of the subscription.
+func WithReceiveSettings(settings pubsub.ReceiveSettings) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.receiveSettings = settings
+	})
+}
+
+// WithMaxOutstandingMessages sets the maximum number of unprocessed messages of the subscription.
+func WithMaxOutstandingMessages(n int) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.receiveSettings.MaxOutstandingMessages = n
+	})
+}
+
+// WithMaxOutstandingBytes sets the maximum size of unprocessed messages of the subscription.
+func WithMaxOutstandingBytes(n int) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.receiveSettings.MaxOutstandingBytes = n
+	})
+}
+
+// WithNumGoroutines sets the number of StreamingPull streams to pull messages from the subscription.
+func WithNumGoroutines(n int) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.receiveSettings.NumGoroutines = n
+	})
+}
+
+// WithMaxExtension sets the maximum period for which the ack deadline of each message is automatically extended.
+func WithMaxExtension(d time.Duration) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.receiveSettings.MaxExtension = d
+	})
+}
+
+// WithSynchronous switches the underlying receiving mechanism to unary Pull.
+// Note that Synchronous mode is deprecated in Pub/Sub client, see pubsub.ReceiveSettings for details.
+func WithSynchronous(synchronous bool) SubscriptionOption {
+	return newSubscriptionOptionFunc(func(so *subscriptionOptions) {
+		so.receiveSettings.Synchronous = synchronous
+	})
+}
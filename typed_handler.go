@@ -0,0 +1,98 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// DecodeError is returned when the message data can't be decoded by the typed message handler.
+// Since retrying the message never succeeds, it's returned marked by Permanent,
+// so that the message is acked or dead-lettered rather than nacked.
+type DecodeError struct {
+	ContentType string
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode message data as '%s' failed: %v", e.ContentType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// TypedMessageHandler defines the message handler receiving the decoded message data.
+type TypedMessageHandler[T any] func(ctx context.Context, m *pubsub.Message, v T) error
+
+// TypedMessageBatchHandler defines the batch message handler receiving the decoded message data.
+// values[i] is the decoded data of messages[i].
+// To handle error for each message, use BatchError as well as MessageBatchHandler.
+type TypedMessageBatchHandler[T any] func(messages []*pubsub.Message, values []T) error
+
+// Typed initializes MessageHandler which decodes the message data into T with the codec.
+// When the codec is CodecRegistry, the codec is selected by the content type attribute of the message.
+// When decoding failed, DecodeError marked by Permanent is returned without calling f.
+func Typed[T any](codec Codec, f TypedMessageHandler[T]) MessageHandler {
+	return func(ctx context.Context, m *pubsub.Message) error {
+		v, err := decode[T](codec, m)
+		if err != nil {
+			return err
+		}
+		return f(ctx, m, v)
+	}
+}
+
+// TypedBatch initializes MessageBatchHandler which decodes the message data into T with the codec.
+// The messages failed to be decoded are excluded from f and DecodeError is set to them in BatchError.
+func TypedBatch[T any](codec Codec, f TypedMessageBatchHandler[T]) MessageBatchHandler {
+	return func(messages []*pubsub.Message) error {
+		batchErr := BatchError{}
+		decodedMessages := make([]*pubsub.Message, 0, len(messages))
+		values := make([]T, 0, len(messages))
+		for _, m := range messages {
+			v, err := decode[T](codec, m)
+			if err != nil {
+				batchErr[m.ID] = err
+				continue
+			}
+			decodedMessages = append(decodedMessages, m)
+			values = append(values, v)
+		}
+		if len(batchErr) == 0 {
+			return f(decodedMessages, values)
+		}
+
+		var err error
+		if len(decodedMessages) > 0 {
+			err = f(decodedMessages, values)
+		}
+		var handlerBatchErr BatchError
+		isBatchErr := errors.As(err, &handlerBatchErr)
+		for _, m := range decodedMessages {
+			if isBatchErr {
+				if e, ok := handlerBatchErr[m.ID]; ok {
+					batchErr[m.ID] = e
+				}
+			} else if err != nil {
+				batchErr[m.ID] = err
+			}
+		}
+		return batchErr
+	}
+}
+
+func decode[T any](codec Codec, m *pubsub.Message) (T, error) {
+	var v T
+	contentType := m.Attributes[ContentTypeAttribute]
+	c, err := codecForContentType(codec, contentType)
+	if err != nil {
+		return v, Permanent(&DecodeError{ContentType: contentType, Err: err})
+	}
+	if err := c.Unmarshal(m.Data, &v); err != nil {
+		return v, Permanent(&DecodeError{ContentType: c.ContentType(), Err: err})
+	}
+	return v, nil
+}
@@ -0,0 +1,114 @@
+package pm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type typedPayload struct {
+	Name string `json:"name"`
+}
+
+func TestTyped(t *testing.T) {
+	t.Parallel()
+
+	protoData, err := ProtobufCodec.Marshal(wrapperspb.String("proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("decodes message data with the codec", func(t *testing.T) {
+		t.Parallel()
+
+		var got typedPayload
+		handler := Typed(JSONCodec, func(ctx context.Context, m *pubsub.Message, v typedPayload) error {
+			got = v
+			return nil
+		})
+		if err := handler(context.Background(), &pubsub.Message{Data: []byte(`{"name":"json"}`)}); err != nil {
+			t.Fatalf("handler() error = %v, want nil", err)
+		}
+		if got.Name != "json" {
+			t.Errorf("handler() decoded = %v, want %v", got.Name, "json")
+		}
+	})
+
+	t.Run("decodes message data with the codec selected by content type", func(t *testing.T) {
+		t.Parallel()
+
+		var got *wrapperspb.StringValue
+		handler := Typed(NewCodecRegistry(JSONCodec, ProtobufCodec), func(ctx context.Context, m *pubsub.Message, v *wrapperspb.StringValue) error {
+			got = v
+			return nil
+		})
+		m := &pubsub.Message{Data: protoData, Attributes: map[string]string{ContentTypeAttribute: ContentTypeProtobuf}}
+		if err := handler(context.Background(), m); err != nil {
+			t.Fatalf("handler() error = %v, want nil", err)
+		}
+		if got.GetValue() != "proto" {
+			t.Errorf("handler() decoded = %v, want %v", got.GetValue(), "proto")
+		}
+	})
+
+	t.Run("returns DecodeError without calling handler when decoding failed", func(t *testing.T) {
+		t.Parallel()
+
+		handler := Typed(JSONCodec, func(ctx context.Context, m *pubsub.Message, v typedPayload) error {
+			t.Error("handler must not be called when decoding failed")
+			return nil
+		})
+		err := handler(context.Background(), &pubsub.Message{Data: []byte("invalid")})
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("handler() error = %v, want DecodeError", err)
+		}
+		if decision, _ := AckDecisionOf(err); decision != AckDecisionPermanent {
+			t.Errorf("AckDecisionOf() = %v, want %v", decision, AckDecisionPermanent)
+		}
+	})
+}
+
+func TestTypedBatch(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("error")
+	handler := TypedBatch(JSONCodec, func(messages []*pubsub.Message, values []typedPayload) error {
+		if len(messages) != len(values) {
+			t.Errorf("handler() got %d messages and %d values", len(messages), len(values))
+		}
+		batchErr := BatchError{}
+		for i, v := range values {
+			if v.Name == "error" {
+				batchErr[messages[i].ID] = wantErr
+			}
+		}
+		return batchErr
+	})
+
+	err := handler([]*pubsub.Message{
+		{ID: "1", Data: []byte(`{"name":"ok"}`)},
+		{ID: "2", Data: []byte(`{"name":"error"}`)},
+		{ID: "3", Data: []byte(`invalid`)},
+	})
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("handler() error = %v, want BatchError", err)
+	}
+	if batchErr["1"] != nil {
+		t.Errorf("handler() error for message 1 = %v, want nil", batchErr["1"])
+	}
+	if batchErr["2"] != wantErr {
+		t.Errorf("handler() error for message 2 = %v, want %v", batchErr["2"], wantErr)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(batchErr["3"], &decodeErr) {
+		t.Errorf("handler() error for message 3 = %v, want DecodeError", batchErr["3"])
+	}
+	if decision, _ := AckDecisionOf(batchErr["3"]); decision != AckDecisionPermanent {
+		t.Errorf("AckDecisionOf() for message 3 = %v, want %v", decision, AckDecisionPermanent)
+	}
+}